@@ -5,27 +5,36 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/confluentinc/confluent-kafka-go/kafka"
-	"github.com/go-redis/redis/v9"
-	"github.com/go-zookeeper/zk"
-	"github.com/gocql/gocql"
 	"github.com/gorilla/mux"
 	"github.com/hugosrc/shortlink/config"
-	"github.com/hugosrc/shortlink/internal/adapter/cassandra"
-	"github.com/hugosrc/shortlink/internal/adapter/cassandra/repository"
+	authAdapter "github.com/hugosrc/shortlink/internal/adapter/auth"
+	"github.com/hugosrc/shortlink/internal/adapter/bolt"
+	"github.com/hugosrc/shortlink/internal/adapter/file"
+	"github.com/hugosrc/shortlink/internal/adapter/humancheck"
 	kafkaAdapter "github.com/hugosrc/shortlink/internal/adapter/kafka"
 	"github.com/hugosrc/shortlink/internal/adapter/keycloak"
+	"github.com/hugosrc/shortlink/internal/adapter/memory"
+	"github.com/hugosrc/shortlink/internal/adapter/oidc"
+	"github.com/hugosrc/shortlink/internal/adapter/preview"
+	"github.com/hugosrc/shortlink/internal/adapter/qrcode"
 	redisAdapter "github.com/hugosrc/shortlink/internal/adapter/redis"
+	"github.com/hugosrc/shortlink/internal/adapter/storage"
+	"github.com/hugosrc/shortlink/internal/adapter/urlscan"
 	"github.com/hugosrc/shortlink/internal/adapter/zookeeper"
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/policy"
+	"github.com/hugosrc/shortlink/internal/core/port"
 	"github.com/hugosrc/shortlink/internal/core/service"
 	"github.com/hugosrc/shortlink/internal/handler/rest"
 	"github.com/jxskiss/base62"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
@@ -42,32 +51,45 @@ func main() {
 		os.Exit(1)
 	}
 
-	cassandraConn, err := cassandra.New(config)
+	var infra *infrastructure
+	if config.GetString("SHORTLINK_MODE") == "standalone" {
+		infra, err = newStandalone(config)
+	} else {
+		infra, err = newDistributed(config, logger)
+	}
 	if err != nil {
-		logger.Error("couldn't connect to cassandra", zap.Error(err))
+		logger.Error("couldn't initialize the infrastructure", zap.Error(err))
 		os.Exit(1)
 	}
 
-	redisConn, err := redisAdapter.New(config)
+	rateLimits, err := rest.ParseRateLimitPolicies(config.GetString("RATE_LIMIT_POLICIES"))
 	if err != nil {
-		logger.Error("couldn't connect to redis", zap.Error(err))
+		logger.Error("couldn't parse rate limit policies", zap.Error(err))
 		os.Exit(1)
 	}
 
-	zookeeperConn, err := zookeeper.New(config)
+	trustedProxies, err := rest.ParseTrustedProxies(config.GetString("TRUSTED_PROXIES"))
 	if err != nil {
-		logger.Error("couldn't connect to zookeeper", zap.Error(err))
+		logger.Error("couldn't parse trusted proxies", zap.Error(err))
 		os.Exit(1)
 	}
 
-	keycloakAuth := keycloak.NewOpenIDAuth(config)
-
-	kafkaProducer, err := kafkaAdapter.NewProducer(config)
-	if err != nil {
-		logger.Error("couldn't connect to kafka", zap.Error(err))
+	trashRetention := config.GetDuration("TRASH_RETENTION")
+	if trashRetention < 0 {
+		logger.Error("trash retention can't be negative", zap.Duration("retention", trashRetention))
 		os.Exit(1)
 	}
 
+	var defaultRateLimit *domain.RateLimit
+	if value := config.GetString("RATE_LIMIT_DEFAULT"); len(value) > 0 {
+		limit, err := rest.ParseRateLimit(value)
+		if err != nil {
+			logger.Error("couldn't parse default rate limit", zap.Error(err))
+			os.Exit(1)
+		}
+		defaultRateLimit = &limit
+	}
+
 	logMiddleware := func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			logger.Info("received request", zap.String("method", r.Method), zap.String("uri", r.RequestURI))
@@ -75,15 +97,42 @@ func main() {
 		})
 	}
 
+	infra.Storage.Search = &loggedIndex{LinkIndex: infra.Storage.Search, logger: logger}
+	infra.Storage.Audit = &loggedAudit{AuditRepository: infra.Storage.Audit, logger: logger}
+
 	server := newServer(serverConf{
-		Address:      fmt.Sprintf(":%d", 3000),
-		Auth:         keycloakAuth,
-		Cassandra:    cassandraConn,
-		Redis:        redisConn,
-		Zookeeper:    zookeeperConn,
-		Kafka:        kafkaProducer,
-		MetricsTopic: config.GetString("KAFKA_METRICS_PRODUCER_TOPIC_NAME"),
-		Middlewares:  []func(next http.Handler) http.Handler{logMiddleware},
+		Address:   fmt.Sprintf(":%d", 3000),
+		BaseURL:   config.GetString("SHORTLINK_BASE_URL"),
+		Auth:      infra.Auth,
+		Directory: infra.Directory,
+		Policy: policy.New(
+			config.GetString("AUTH_READ_SCOPE"),
+			config.GetString("AUTH_WRITE_SCOPE"),
+			config.GetString("AUTH_ADMIN_ROLE"),
+		),
+		Storage:  infra.Storage,
+		Counter:  infra.Counter,
+		Caching:  infra.Caching,
+		Clicks:   infra.Clicks,
+		Producer: infra.Producer,
+		Preview: previewConf{
+			Fetcher:   preview.NewHTTPFetcher(config),
+			Workers:   config.GetInt("PREVIEW_WORKERS"),
+			QueueSize: config.GetInt("PREVIEW_QUEUE_SIZE"),
+			Timeout:   config.GetDuration("PREVIEW_FETCH_TIMEOUT"),
+		},
+		Trash: trashConf{
+			Retention:     trashRetention,
+			PurgeInterval: config.GetDuration("TRASH_PURGE_INTERVAL"),
+		},
+		Anonymous: newAnonymousGuard(config, infra.RateLimiter, infra.Replays),
+		RateLimit: rateLimitConf{
+			Limiter:  infra.RateLimiter,
+			Policies: rateLimits,
+			Default:  defaultRateLimit,
+		},
+		Middlewares:    []func(next http.Handler) http.Handler{logMiddleware},
+		TrustedProxies: trustedProxies,
 	})
 
 	go func() {
@@ -93,7 +142,7 @@ func main() {
 		}
 	}()
 
-	done := make(chan os.Signal)
+	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 	<-done
@@ -103,13 +152,7 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 
 	defer func() {
-		if err := redisConn.Close(); err != nil {
-			logger.Error("error closing redis connection", zap.Error(err))
-		}
-
-		cassandraConn.Close()
-		zookeeperConn.Close()
-		kafkaProducer.Close()
+		infra.Close(logger)
 		cancel()
 		close(done)
 	}()
@@ -122,38 +165,317 @@ func main() {
 	logger.Info("shutdown performed successfully")
 }
 
+// infrastructure holds the adapters the service runs on: the distributed
+// services, or the embedded ones of the standalone mode.
+type infrastructure struct {
+	Storage     *storage.Storage
+	Counter     port.Counter
+	Caching     port.LinkCaching
+	Clicks      port.ClickCounter
+	Producer    port.MetricsProducer
+	RateLimiter port.RateLimiter
+	Replays     port.ReplayGuard
+	Auth        port.Auth
+	Directory   port.UserDirectory
+
+	closers []func() error
+}
+
+func (i *infrastructure) onClose(closer func() error) {
+	i.closers = append(i.closers, closer)
+}
+
+// Close releases the adapters in the reverse order they were created.
+func (i *infrastructure) Close(logger *zap.Logger) {
+	for j := len(i.closers) - 1; j >= 0; j-- {
+		if err := i.closers[j](); err != nil {
+			logger.Error("error closing connection", zap.Error(err))
+		}
+	}
+}
+
+// newDistributed connects to the database of STORAGE_BACKEND, Redis,
+// ZooKeeper, Kafka and the identity provider.
+func newDistributed(config *viper.Viper, logger *zap.Logger) (*infrastructure, error) {
+	if storage.MigrateOnStartup(config) {
+		if err := migrate(config, logger); err != nil {
+			return nil, fmt.Errorf("migrate database schema: %w", err)
+		}
+	}
+
+	store, err := storage.Open(config)
+	if err != nil {
+		return nil, fmt.Errorf("connect to the database: %w", err)
+	}
+
+	redisConn, err := redisAdapter.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("connect to redis: %w", err)
+	}
+
+	zookeeperConn, err := zookeeper.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("connect to zookeeper: %w", err)
+	}
+
+	tokenAuth, directory, err := newAuth(config)
+	if err != nil {
+		return nil, fmt.Errorf("configure authentication: %w", err)
+	}
+
+	kafkaProducer, err := kafkaAdapter.NewProducer(config)
+	if err != nil {
+		return nil, fmt.Errorf("connect to kafka: %w", err)
+	}
+
+	counter := zookeeper.NewCounter(zookeeperConn)
+	_ = counter.UpdateCounterBase() // TODO: goroutine
+
+	infra := &infrastructure{
+		Storage:     store,
+		Counter:     counter,
+		Caching:     redisAdapter.NewRedisCaching(redisConn),
+		Clicks:      redisAdapter.NewRedisClickCounter(redisConn),
+		Producer:    kafkaAdapter.NewKafkaMetricsProducer(config.GetString("KAFKA_METRICS_PRODUCER_TOPIC_NAME"), kafkaProducer),
+		RateLimiter: redisAdapter.NewRedisRateLimiter(redisConn, memory.NewRateLimiter()),
+		Replays:     redisAdapter.NewRedisReplayGuard(redisConn, memory.NewReplayGuard()),
+		Auth:        tokenAuth,
+		Directory:   directory,
+	}
+
+	infra.onClose(redisConn.Close)
+	infra.onClose(func() error { store.Close(); return nil })
+	infra.onClose(func() error { zookeeperConn.Close(); return nil })
+	infra.onClose(func() error { kafkaProducer.Close(); return nil })
+	infra.onClose(func() error { tokenAuth.Close(); return nil })
+
+	return infra, nil
+}
+
+// newStandalone runs the whole service in one process: links, workspaces,
+// API keys, the hash counter and click counts are stored in the bolt
+// database of BOLT_PATH, links are cached in memory, redirect metrics are
+// appended to METRICS_FILE and users authenticate with static tokens.
+func newStandalone(config *viper.Viper) (*infrastructure, error) {
+	db, err := bolt.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("open the database: %w", err)
+	}
+	store := storage.NewBolt(db)
+
+	producer, err := file.NewMetricsProducer(config.GetString("METRICS_FILE"))
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("open the metrics file: %w", err)
+	}
+
+	tokenAuth, err := authAdapter.NewStaticTokenAuth(config)
+	if err != nil {
+		store.Close()
+		_ = producer.Close()
+		return nil, fmt.Errorf("configure authentication: %w", err)
+	}
+
+	infra := &infrastructure{
+		Storage:     store,
+		Counter:     bolt.NewCounter(db, config.GetInt("BOLT_COUNTER_RANGE")),
+		Caching:     memory.NewLinkCaching(config.GetInt("MEMORY_CACHE_SIZE")),
+		Clicks:      bolt.NewClickCounter(db),
+		Producer:    producer,
+		RateLimiter: memory.NewRateLimiter(),
+		Replays:     memory.NewReplayGuard(),
+		Auth:        tokenAuth,
+		Directory:   tokenAuth,
+	}
+
+	// the metrics file is closed last, after redirects still in flight
+	// have written their metrics
+	infra.onClose(producer.Close)
+	infra.onClose(func() error { store.Close(); return nil })
+
+	return infra, nil
+}
+
+// migrate applies the pending schema migrations. Replicas started together
+// wait for the one holding the migration lock.
+func migrate(config *viper.Viper, logger *zap.Logger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	applied, err := storage.Migrate(ctx, config)
+	for _, m := range applied {
+		logger.Info("migration applied", zap.Int("version", m.Version), zap.String("name", m.Name))
+	}
+
+	return err
+}
+
+// newAuth creates the bearer token authenticator of the provider selected by
+// AUTH_PROVIDER: keycloak, the default, or any other OpenID Connect provider.
+func newAuth(config *viper.Viper) (*oidc.Auth, port.UserDirectory, error) {
+	switch provider := config.GetString("AUTH_PROVIDER"); provider {
+	case "", "keycloak":
+		auth, err := keycloak.NewOpenIDAuth(config)
+		return auth, keycloak.NewUserDirectory(config), err
+	case "oidc":
+		auth, err := oidc.New(oidc.NewConfig(config))
+		return auth, oidc.NopDirectory{}, err
+	default:
+		return nil, nil, fmt.Errorf("unknown auth provider %q", provider)
+	}
+}
+
+// newAnonymousGuard enables link creation without credentials when
+// ANONYMOUS_LINKS_ENABLED is set. HUMAN_VERIFIER optionally requires a
+// CAPTCHA ("captcha") or a proof of work ("pow") with each link.
+func newAnonymousGuard(config *viper.Viper, limiter port.RateLimiter, replays port.ReplayGuard) *service.AnonymousGuard {
+	if !config.GetBool("ANONYMOUS_LINKS_ENABLED") {
+		return nil
+	}
+
+	var verifier port.HumanVerifier
+	switch config.GetString("HUMAN_VERIFIER") {
+	case "captcha":
+		verifier = humancheck.NewCaptchaVerifier(config)
+	case "pow":
+		verifier = humancheck.NewProofOfWork(config.GetInt("POW_DIFFICULTY"), replays)
+	}
+
+	return service.NewAnonymousGuard(
+		urlscan.NewLocalScanner(config),
+		limiter,
+		verifier,
+		domain.RateLimit{
+			Limit:  config.GetInt("ANONYMOUS_RATE_LIMIT"),
+			Period: config.GetDuration("ANONYMOUS_RATE_PERIOD"),
+		},
+		config.GetDuration("ANONYMOUS_LINK_TTL"),
+		config.GetDuration("ANONYMOUS_LINK_MAX_TTL"),
+	)
+}
+
 type serverConf struct {
-	Address      string
-	Auth         *keycloak.OpenIDAuth
-	Cassandra    *gocql.Session
-	Redis        *redis.Client
-	Zookeeper    *zk.Conn
-	Kafka        *kafka.Producer
-	MetricsTopic string
-	Middlewares  []func(next http.Handler) http.Handler
+	Address     string
+	BaseURL     string
+	Auth        port.Auth
+	Directory   port.UserDirectory
+	Policy      *policy.Policy
+	Storage     *storage.Storage
+	Counter     port.Counter
+	Caching     port.LinkCaching
+	Clicks      port.ClickCounter
+	Producer    port.MetricsProducer
+	Preview     previewConf
+	Trash       trashConf
+	Anonymous   *service.AnonymousGuard
+	RateLimit   rateLimitConf
+	Middlewares []func(next http.Handler) http.Handler
+	// TrustedProxies relay the client address in X-Forwarded-For.
+	TrustedProxies []*net.IPNet
+}
+
+// loggedIndex logs the failures to update the search index, which don't
+// fail the changes of the links. Running "migrate reindex" adds the links
+// that are missing.
+type loggedIndex struct {
+	port.LinkIndex
+	logger *zap.Logger
+}
+
+func (i *loggedIndex) Index(ctx context.Context, link *domain.Link) error {
+	err := i.LinkIndex.Index(ctx, link)
+	if err != nil {
+		i.logger.Error("couldn't index link", zap.String("hash", link.Hash), zap.Error(err))
+	}
+
+	return err
+}
+
+func (i *loggedIndex) Remove(ctx context.Context, link *domain.Link) error {
+	err := i.LinkIndex.Remove(ctx, link)
+	if err != nil {
+		i.logger.Error("couldn't remove link from the index", zap.String("hash", link.Hash), zap.Error(err))
+	}
+
+	return err
+}
+
+// loggedAudit logs the failures to record audit events, which don't fail
+// the changes they describe since those are already stored.
+type loggedAudit struct {
+	port.AuditRepository
+	logger *zap.Logger
+}
+
+func (a *loggedAudit) Append(ctx context.Context, event *domain.AuditEvent) error {
+	err := a.AuditRepository.Append(ctx, event)
+	if err != nil {
+		a.logger.Error("couldn't record audit event",
+			zap.String("hash", event.Hash), zap.String("action", event.Action), zap.Error(err))
+	}
+
+	return err
+}
+
+type rateLimitConf struct {
+	Limiter  port.RateLimiter
+	Policies []rest.RateLimitPolicy
+	Default  *domain.RateLimit
+}
+
+type trashConf struct {
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+type previewConf struct {
+	Fetcher   port.MetadataFetcher
+	Workers   int
+	QueueSize int
+	Timeout   time.Duration
 }
 
 func newServer(conf serverConf) *http.Server {
 	r := mux.NewRouter()
+	r.Use(rest.TrustedProxyMiddleware(conf.TrustedProxies))
+	r.Use(rest.RequestMetadataMiddleware)
 
 	for _, middleware := range conf.Middlewares {
 		r.Use(middleware)
 	}
 
-	counter := zookeeper.NewCounter(conf.Zookeeper)
-	_ = counter.UpdateCounterBase() // TODO: goroutine
-
 	encoder := base62.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789")
-	caching := redisAdapter.NewRedisCaching(conf.Redis)
-	repo := repository.NewLinkRepository(conf.Cassandra)
+	caching := conf.Caching
+	repo := conf.Storage.Links
+
+	previewWorker := service.NewPreviewWorker(conf.Preview.Fetcher, caching, repo,
+		conf.Preview.Workers, conf.Preview.QueueSize, conf.Preview.Timeout)
+	previewWorker.Start()
 
-	service := service.NewLinkService(counter, encoder, caching, repo)
+	apiKeyService := service.NewAPIKeyService(conf.Storage.APIKeys)
+	auth := authAdapter.NewCompositeAuth(conf.Auth, apiKeyService)
 
-	metricsProducer := kafkaAdapter.NewKafkaMetricsProducer(conf.MetricsTopic, conf.Kafka)
+	r.Use(rest.RateLimitMiddleware(auth, conf.RateLimit.Limiter, conf.RateLimit.Policies, conf.RateLimit.Default))
 
-	rest.NewLinkHandler(conf.Auth, metricsProducer, service).Register(r)
+	workspaceRepo := conf.Storage.Workspaces
+	workspaceService := service.NewWorkspaceService(workspaceRepo, conf.Policy)
 
-	return &http.Server{
+	auditRepo := conf.Storage.Audit
+	auditService := service.NewAuditService(auditRepo, repo, workspaceRepo, conf.Policy)
+
+	trashPurger := service.NewTrashPurger(repo, caching, auditRepo, conf.Trash.Retention, conf.Trash.PurgeInterval)
+	trashPurger.Start()
+
+	service := service.NewLinkService(conf.Counter, encoder, caching, repo, previewWorker, conf.Clicks, conf.Directory,
+		workspaceRepo, conf.Policy, conf.Anonymous, conf.Trash.Retention, auditRepo, conf.Storage.Search)
+
+	rest.NewAPIKeyHandler(auth, apiKeyService).Register(r)
+	rest.NewWorkspaceHandler(auth, workspaceService).Register(r)
+	rest.NewAuditHandler(auth, auditService).Register(r)
+	rest.NewQRCodeHandler(conf.BaseURL, qrcode.NewGenerator(), service).Register(r)
+	rest.NewLinkHandler(auth, conf.Producer, service).Register(r)
+
+	server := &http.Server{
 		Addr:              conf.Address,
 		Handler:           r,
 		ReadTimeout:       5 * time.Second,
@@ -161,4 +483,9 @@ func newServer(conf serverConf) *http.Server {
 		IdleTimeout:       5 * time.Second,
 		ReadHeaderTimeout: 5 * time.Second,
 	}
+
+	server.RegisterOnShutdown(previewWorker.Stop)
+	server.RegisterOnShutdown(trashPurger.Stop)
+
+	return server
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hugosrc/shortlink/config"
+	"github.com/hugosrc/shortlink/internal/adapter/storage"
+	"github.com/hugosrc/shortlink/internal/core/service"
+	"go.uber.org/zap"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up       apply the pending migrations
+  verify   check that every migration has been applied
+  reindex  rebuild the search index from the links
+
+The schema of the STORAGE_BACKEND database is migrated.`
+
+func main() {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatalf("couldn't initialize zap logger: %v", err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	config, err := config.Init()
+	if err != nil {
+		logger.Error("couldn't initialize configuration", zap.Error(err))
+		os.Exit(1)
+	}
+
+	backend, err := storage.Backend(config)
+	if err != nil {
+		logger.Error("couldn't configure storage", zap.Error(err))
+		os.Exit(1)
+	}
+	logger = logger.With(zap.String("backend", backend))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := storage.Migrate(ctx, config)
+		for _, m := range applied {
+			logger.Info("migration applied", zap.Int("version", m.Version), zap.String("name", m.Name))
+		}
+
+		if err != nil {
+			logger.Error("couldn't apply migrations", zap.Error(err))
+			os.Exit(1)
+		}
+
+		logger.Info("schema is up to date")
+	case "verify":
+		if err := storage.Verify(ctx, config); err != nil {
+			logger.Error("schema verification failed", zap.Error(err))
+			os.Exit(1)
+		}
+
+		logger.Info("schema is up to date")
+	case "reindex":
+		store, err := storage.Open(config)
+		if err != nil {
+			logger.Error("couldn't open storage", zap.Error(err))
+			os.Exit(1)
+		}
+
+		indexed, err := service.Reindex(ctx, store.Links, store.Search)
+		store.Close()
+		if err != nil {
+			logger.Error("couldn't reindex links", zap.Int("indexed", indexed), zap.Error(err))
+			os.Exit(1)
+		}
+
+		logger.Info("links reindexed", zap.Int("indexed", indexed))
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// CompositeAuth authenticates requests with an API key when the X-API-Key
+// header is present and with the bearer token otherwise. Requests already
+// authenticated by a middleware reuse the principal of their context.
+type CompositeAuth struct {
+	token port.Auth
+	keys  port.APIKeyService
+}
+
+func NewCompositeAuth(token port.Auth, keys port.APIKeyService) *CompositeAuth {
+	return &CompositeAuth{
+		token: token,
+		keys:  keys,
+	}
+}
+
+func (a *CompositeAuth) Authenticate(r *http.Request, w http.ResponseWriter) (*domain.Principal, error) {
+	if principal, ok := domain.PrincipalFromContext(r.Context()); ok {
+		return principal, nil
+	}
+
+	if key := r.Header.Get(APIKeyHeader); len(key) > 0 {
+		return a.keys.Authenticate(r.Context(), key)
+	}
+
+	return a.token.Authenticate(r, w)
+}
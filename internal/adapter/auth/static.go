@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"strings"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/spf13/viper"
+)
+
+// StaticTokenAuth authenticates bearer tokens listed in the configuration,
+// for installs without an identity provider. It also serves as the user
+// directory, naming users after their ids.
+type StaticTokenAuth struct {
+	principals map[[sha256.Size]byte]*domain.Principal
+	names      map[string]string
+}
+
+// NewStaticTokenAuth reads the comma separated <token>=<user id>[:<username>]
+// entries of STATIC_AUTH_TOKENS. Every token is granted the scopes of
+// STATIC_AUTH_SCOPES, and the users of STATIC_AUTH_ADMINS the AUTH_ADMIN_ROLE.
+func NewStaticTokenAuth(conf *viper.Viper) (*StaticTokenAuth, error) {
+	scopes := strings.Fields(conf.GetString("STATIC_AUTH_SCOPES"))
+
+	admins := make(map[string]bool)
+	for _, userID := range strings.Split(conf.GetString("STATIC_AUTH_ADMINS"), ",") {
+		admins[strings.TrimSpace(userID)] = true
+	}
+
+	a := &StaticTokenAuth{
+		principals: make(map[[sha256.Size]byte]*domain.Principal),
+		names:      make(map[string]string),
+	}
+
+	for _, entry := range strings.Split(conf.GetString("STATIC_AUTH_TOKENS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+
+		token, user, ok := cut(entry, "=")
+		if !ok || len(token) == 0 || len(user) == 0 {
+			return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "static token entries must be <token>=<user id>[:<username>]")
+		}
+
+		userID, username, _ := cut(user, ":")
+		if len(username) == 0 {
+			username = userID
+		}
+
+		principal := &domain.Principal{
+			UserID:   userID,
+			Username: username,
+			Scopes:   scopes,
+		}
+		if admins[userID] {
+			principal.Roles = []string{conf.GetString("AUTH_ADMIN_ROLE")}
+		}
+
+		a.principals[sha256.Sum256([]byte(token))] = principal
+		a.names[userID] = username
+	}
+
+	if len(a.principals) == 0 {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "no static tokens configured")
+	}
+
+	return a, nil
+}
+
+// Authenticate looks tokens up by their hash, so the lookup time doesn't
+// depend on how much of a token is right.
+func (a *StaticTokenAuth) Authenticate(r *http.Request, w http.ResponseWriter) (*domain.Principal, error) {
+	fields := strings.Fields(r.Header.Get("Authorization"))
+	if len(fields) != 2 || !strings.EqualFold(fields[0], "Bearer") {
+		return nil, util.NewErrorf(util.ErrCodeUnauthorized, "authorization header does not follow the format 'Authorization: Bearer <token>'")
+	}
+
+	principal, ok := a.principals[sha256.Sum256([]byte(fields[1]))]
+	if !ok {
+		return nil, util.NewErrorf(util.ErrCodeUnauthorized, "unknown token")
+	}
+
+	authenticated := *principal
+	return &authenticated, nil
+}
+
+func (a *StaticTokenAuth) DisplayName(ctx context.Context, userID string) (string, error) {
+	return a.names[userID], nil
+}
+
+// cut is strings.Cut, which isn't available before go 1.18.
+func cut(s string, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+
+	return s, "", false
+}
@@ -0,0 +1,141 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+	"go.etcd.io/bbolt"
+)
+
+// apiKeyRecord stores the secret hash, which isn't part of the key's JSON.
+type apiKeyRecord struct {
+	domain.APIKey
+	Hash string `json:"hash"`
+}
+
+type APIKeyRepository struct {
+	db *bbolt.DB
+}
+
+func NewAPIKeyRepository(db *bbolt.DB) port.APIKeyRepository {
+	return &APIKeyRepository{
+		db: db,
+	}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	if err := r.db.Update(func(tx *bbolt.Tx) error {
+		if err := putAPIKey(tx, key); err != nil {
+			return err
+		}
+
+		return tx.Bucket(apiKeysByUserIndex).Put(compositeKey(key.UserID, key.ID), []byte(key.ID))
+	}); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting api key")
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepository) FindByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	var key *domain.APIKey
+	if err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		key, err = findAPIKey(tx, id)
+		return err
+	}); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving api key")
+	}
+
+	if key == nil {
+		return nil, util.NewErrorf(util.ErrCodeNotFound, "api key not found")
+	}
+
+	return key, nil
+}
+
+func (r *APIKeyRepository) ListByUser(ctx context.Context, userID string) ([]*domain.APIKey, error) {
+	keys := []*domain.APIKey{}
+	if err := r.db.View(func(tx *bbolt.Tx) error {
+		prefix := keyPrefix(userID)
+		c := tx.Bucket(apiKeysByUserIndex).Cursor()
+		for k, id := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, id = c.Next() {
+			key, err := findAPIKey(tx, string(id))
+			if err != nil {
+				return err
+			}
+
+			if key != nil {
+				keys = append(keys, key)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing api keys")
+	}
+
+	return keys, nil
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	if err := r.update(id, func(key *domain.APIKey) {
+		key.RevokedAt = &at
+	}); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error revoking api key")
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepository) UpdateLastUsed(ctx context.Context, id string, at time.Time) error {
+	if err := r.update(id, func(key *domain.APIKey) {
+		key.LastUsedAt = &at
+	}); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error updating api key")
+	}
+
+	return nil
+}
+
+// update applies change to the stored key, if it exists.
+func (r *APIKeyRepository) update(id string, change func(key *domain.APIKey)) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		key, err := findAPIKey(tx, id)
+		if err != nil || key == nil {
+			return err
+		}
+
+		change(key)
+		return putAPIKey(tx, key)
+	})
+}
+
+func putAPIKey(tx *bbolt.Tx, key *domain.APIKey) error {
+	data, err := json.Marshal(&apiKeyRecord{APIKey: *key, Hash: key.Hash})
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket(apiKeysBucket).Put([]byte(key.ID), data)
+}
+
+func findAPIKey(tx *bbolt.Tx, id string) (*domain.APIKey, error) {
+	data := tx.Bucket(apiKeysBucket).Get([]byte(id))
+	if data == nil {
+		return nil, nil
+	}
+
+	var record apiKeyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	record.APIKey.Hash = record.Hash
+	return &record.APIKey, nil
+}
@@ -0,0 +1,117 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+	"go.etcd.io/bbolt"
+)
+
+// AuditRepository keys events by time, so they are kept in order, and
+// indexes them by link.
+type AuditRepository struct {
+	db *bbolt.DB
+}
+
+func NewAuditRepository(db *bbolt.DB) port.AuditRepository {
+	return &AuditRepository{
+		db: db,
+	}
+}
+
+func (r *AuditRepository) Append(ctx context.Context, event *domain.AuditEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "json marshal error")
+	}
+
+	key := append(encodeUint(uint64(event.Time.UnixNano())), event.ID...)
+
+	if err := r.db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(auditBucket).Put(key, data); err != nil {
+			return err
+		}
+
+		return tx.Bucket(auditByHashIndex).Put(compositeKey(event.Hash, string(key)), key)
+	}); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting audit event")
+	}
+
+	return nil
+}
+
+func (r *AuditRepository) ListByHash(ctx context.Context, hash string, limit int) ([]*domain.AuditEvent, error) {
+	return r.Query(ctx, &domain.AuditFilter{Hash: hash, Limit: limit})
+}
+
+// Query walks the events backwards, from the end of the period or from the
+// last event of the link.
+func (r *AuditRepository) Query(ctx context.Context, filter *domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	events := []*domain.AuditEvent{}
+
+	collect := func(data []byte) error {
+		var event domain.AuditEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return err
+		}
+
+		if filter.Matches(&event) {
+			events = append(events, &event)
+		}
+
+		return nil
+	}
+
+	if err := r.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(auditBucket)
+
+		if len(filter.Hash) > 0 {
+			prefix := keyPrefix(filter.Hash)
+
+			var keys [][]byte
+			c := tx.Bucket(auditByHashIndex).Cursor()
+			for k, key := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, key = c.Next() {
+				keys = append(keys, key)
+			}
+
+			for i := len(keys) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+				if err := collect(bucket.Get(keys[i])); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		c := bucket.Cursor()
+
+		k, data := c.Last()
+		if !filter.Until.IsZero() {
+			if k, _ = c.Seek(encodeUint(uint64(filter.Until.UnixNano()))); k == nil {
+				k, data = c.Last()
+			} else {
+				k, data = c.Prev()
+			}
+		}
+
+		for ; k != nil && len(events) < filter.Limit; k, data = c.Prev() {
+			if !filter.Since.IsZero() && decodeUint(k[:8]) < uint64(filter.Since.UnixNano()) {
+				break
+			}
+
+			if err := collect(data); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing audit events")
+	}
+
+	return events, nil
+}
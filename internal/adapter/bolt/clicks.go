@@ -0,0 +1,55 @@
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/hugosrc/shortlink/internal/util"
+	"go.etcd.io/bbolt"
+)
+
+type ClickCounter struct {
+	db *bbolt.DB
+}
+
+func NewClickCounter(db *bbolt.DB) *ClickCounter {
+	return &ClickCounter{
+		db: db,
+	}
+}
+
+// Incr batches concurrent increments into a single write transaction.
+func (c *ClickCounter) Incr(ctx context.Context, hash string) error {
+	if err := c.db.Batch(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(clicksBucket)
+		return bucket.Put([]byte(hash), encodeUint(decodeUint(bucket.Get([]byte(hash)))+1))
+	}); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error incrementing clicks")
+	}
+
+	return nil
+}
+
+func (c *ClickCounter) Count(ctx context.Context, hash string) (int64, error) {
+	var count uint64
+	_ = c.db.View(func(tx *bbolt.Tx) error {
+		count = decodeUint(tx.Bucket(clicksBucket).Get([]byte(hash)))
+		return nil
+	})
+
+	return int64(count), nil
+}
+
+func encodeUint(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func decodeUint(b []byte) uint64 {
+	if len(b) != 8 {
+		return 0
+	}
+
+	return binary.BigEndian.Uint64(b)
+}
@@ -0,0 +1,71 @@
+package bolt
+
+import (
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/spf13/viper"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	linksBucket           = []byte("links")
+	linksTrashIndex       = []byte("links_trash")
+	linksByOwnerIndex     = []byte("links_by_owner")
+	workspacesBucket      = []byte("workspaces")
+	workspaceMembersIndex = []byte("workspace_members")
+	workspacesByUserIndex = []byte("workspaces_by_user")
+	apiKeysBucket         = []byte("api_keys")
+	apiKeysByUserIndex    = []byte("api_keys_by_user")
+	countersBucket        = []byte("counters")
+	clicksBucket          = []byte("clicks")
+	auditBucket           = []byte("audit")
+	auditByHashIndex      = []byte("audit_by_hash")
+)
+
+// New opens the database file of BOLT_PATH, creating it and its buckets if
+// needed. The file is locked, so only one process can use it at a time.
+func New(conf *viper.Viper) (*bbolt.DB, error) {
+	db, err := bbolt.Open(conf.GetString("BOLT_PATH"), 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error opening bolt database")
+	}
+
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range [][]byte{
+			linksBucket,
+			linksTrashIndex,
+			linksByOwnerIndex,
+			workspacesBucket,
+			workspaceMembersIndex,
+			workspacesByUserIndex,
+			apiKeysBucket,
+			apiKeysByUserIndex,
+			countersBucket,
+			clicksBucket,
+			auditBucket,
+			auditByHashIndex,
+		} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		_ = db.Close()
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error creating bolt buckets")
+	}
+
+	return db, nil
+}
+
+// compositeKey joins the parts of keys made of several identifiers, so
+// the entries sharing the first part can be scanned by prefix.
+func compositeKey(first string, second string) []byte {
+	return []byte(first + "\x00" + second)
+}
+
+func keyPrefix(first string) []byte {
+	return []byte(first + "\x00")
+}
@@ -0,0 +1,74 @@
+package bolt
+
+import (
+	"sync"
+
+	"github.com/hugosrc/shortlink/internal/util"
+	"go.etcd.io/bbolt"
+)
+
+var linkCounterKey = []byte("links")
+
+// counterStart is the first value issued, the first one of the default
+// ZooKeeper counter range, so hashes are as long as in distributed mode.
+const counterStart = 100000
+
+// Counter is a persistent counter for a single process. Values are
+// reserved in ranges, so a restart skips the rest of the current range
+// instead of writing the database on every increment.
+type Counter struct {
+	mu   sync.Mutex
+	db   *bbolt.DB
+	size int
+	next int
+	end  int
+}
+
+func NewCounter(db *bbolt.DB, size int) *Counter {
+	if size <= 0 {
+		size = 1
+	}
+
+	return &Counter{
+		db:   db,
+		size: size,
+	}
+}
+
+func (c *Counter) Inc() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.next == c.end {
+		if err := c.reserve(); err != nil {
+			return 0, err
+		}
+	}
+
+	value := c.next
+	c.next++
+
+	return value, nil
+}
+
+// reserve stores the end of the next range before handing out its values.
+func (c *Counter) reserve() error {
+	return c.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(countersBucket)
+
+		start := counterStart
+		if data := bucket.Get(linkCounterKey); data != nil {
+			start = int(decodeUint(data))
+		}
+
+		end := start + c.size
+		if err := bucket.Put(linkCounterKey, encodeUint(uint64(end))); err != nil {
+			return util.WrapErrorf(err, util.ErrCodeUnknown, "error reserving counter range")
+		}
+
+		c.next = start
+		c.end = end
+
+		return nil
+	})
+}
@@ -0,0 +1,317 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+	"go.etcd.io/bbolt"
+)
+
+// LinkRepository stores each link as a JSON document keyed by its hash.
+type LinkRepository struct {
+	db *bbolt.DB
+}
+
+func NewLinkRepository(db *bbolt.DB) port.LinkRepository {
+	return &LinkRepository{
+		db: db,
+	}
+}
+
+func (r *LinkRepository) Create(ctx context.Context, link *domain.Link) error {
+	data, err := json.Marshal(link)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "json marshal error")
+	}
+
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(linksBucket)
+		if bucket.Get([]byte(link.Hash)) != nil {
+			return util.NewErrorf(util.ErrCodeInvalidArgument, "hash %s is already in use", link.Hash)
+		}
+
+		if err := bucket.Put([]byte(link.Hash), data); err != nil {
+			return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting url")
+		}
+
+		return nil
+	})
+}
+
+func (r *LinkRepository) FindByHash(ctx context.Context, hash string) (*domain.Link, error) {
+	var link *domain.Link
+	if err := r.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		link, err = findLink(tx, hash)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if link == nil {
+		return nil, util.NewErrorf(util.ErrCodeNotFound, "url not found")
+	}
+
+	return link, nil
+}
+
+// Delete removes the link permanently, along with its trash entry.
+func (r *LinkRepository) Delete(ctx context.Context, hash string) error {
+	if err := r.db.Update(func(tx *bbolt.Tx) error {
+		link, err := findLink(tx, hash)
+		if err != nil || link == nil {
+			return err
+		}
+
+		if err := tx.Bucket(linksTrashIndex).Delete(compositeKey(link.UserID, hash)); err != nil {
+			return err
+		}
+
+		return tx.Bucket(linksBucket).Delete([]byte(hash))
+	}); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting url")
+	}
+
+	return nil
+}
+
+// Update replaces the editable attributes of an existing link.
+func (r *LinkRepository) Update(ctx context.Context, link *domain.Link) error {
+	if err := r.db.Update(func(tx *bbolt.Tx) error {
+		return updateVersion(tx, link, func(stored *domain.Link) {
+			stored.OriginalURL = link.OriginalURL
+			stored.UTM = link.UTM
+			stored.ForwardQuery = link.ForwardQuery
+			stored.Rules = link.Rules
+			stored.Variants = link.Variants
+			stored.Metadata = link.Metadata
+			stored.SocialCard = link.SocialCard
+			stored.Title = link.Title
+			stored.Description = link.Description
+			stored.Tags = link.Tags
+			stored.Notes = link.Notes
+			stored.Disabled = link.Disabled
+		})
+	}); err != nil {
+		return err
+	}
+
+	link.Version++
+
+	return nil
+}
+
+func (r *LinkRepository) UpdateMetadata(ctx context.Context, link *domain.Link, metadata *domain.LinkMetadata) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		if err := checkVersion(tx, link); err != nil {
+			return err
+		}
+
+		return updateLink(tx, link.Hash, func(stored *domain.Link) {
+			stored.Metadata = metadata
+		})
+	})
+}
+
+// SoftDelete marks the link as deleted and indexes it in the trash of its
+// owner.
+func (r *LinkRepository) SoftDelete(ctx context.Context, link *domain.Link, at time.Time) error {
+	if err := r.db.Update(func(tx *bbolt.Tx) error {
+		if err := updateVersion(tx, link, func(stored *domain.Link) {
+			stored.DeletedAt = &at
+		}); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(linksTrashIndex).Put(compositeKey(link.UserID, link.Hash), []byte(link.Hash)); err != nil {
+			return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting url")
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	link.Version++
+
+	return nil
+}
+
+func (r *LinkRepository) Restore(ctx context.Context, link *domain.Link) error {
+	if err := r.db.Update(func(tx *bbolt.Tx) error {
+		if err := updateVersion(tx, link, func(stored *domain.Link) {
+			stored.DeletedAt = nil
+		}); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(linksTrashIndex).Delete(compositeKey(link.UserID, link.Hash)); err != nil {
+			return util.WrapErrorf(err, util.ErrCodeUnknown, "error restoring url")
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	link.Version++
+
+	return nil
+}
+
+func (r *LinkRepository) Purge(ctx context.Context, link *domain.Link) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		if err := checkVersion(tx, link); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(linksTrashIndex).Delete(compositeKey(link.UserID, link.Hash)); err != nil {
+			return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting url")
+		}
+
+		if err := tx.Bucket(linksBucket).Delete([]byte(link.Hash)); err != nil {
+			return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting url")
+		}
+
+		return nil
+	})
+}
+
+func (r *LinkRepository) ListDeleted(ctx context.Context, userID string) ([]*domain.Link, error) {
+	var links []*domain.Link
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		prefix := keyPrefix(userID)
+		c := tx.Bucket(linksTrashIndex).Cursor()
+		for k, hash := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, hash = c.Next() {
+			link, err := findLink(tx, string(hash))
+			if err != nil {
+				return err
+			}
+
+			if link != nil {
+				links = append(links, link)
+			}
+		}
+
+		return nil
+	})
+
+	return links, err
+}
+
+func (r *LinkRepository) ListDeletedBefore(ctx context.Context, before time.Time) ([]*domain.Link, error) {
+	var links []*domain.Link
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(linksTrashIndex).ForEach(func(k, hash []byte) error {
+			link, err := findLink(tx, string(hash))
+			if err != nil {
+				return err
+			}
+
+			if link != nil && link.Deleted() && link.DeletedAt.Before(before) {
+				links = append(links, link)
+			}
+
+			return nil
+		})
+	})
+
+	return links, err
+}
+
+// Each reads the links in a single transaction before calling fn, which
+// may then write to the database.
+func (r *LinkRepository) Each(ctx context.Context, fn func(link *domain.Link) error) error {
+	var links []*domain.Link
+	if err := r.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(linksBucket).ForEach(func(k, data []byte) error {
+			var link domain.Link
+			if err := json.Unmarshal(data, &link); err != nil {
+				return util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
+			}
+
+			links = append(links, &link)
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateVersion applies change to the stored link while it is still at the
+// version of link, incrementing the stored version.
+func updateVersion(tx *bbolt.Tx, link *domain.Link, change func(stored *domain.Link)) error {
+	if err := checkVersion(tx, link); err != nil {
+		return err
+	}
+
+	return updateLink(tx, link.Hash, func(stored *domain.Link) {
+		change(stored)
+		stored.Version++
+	})
+}
+
+// checkVersion fails unless the stored link is still at the version of link.
+func checkVersion(tx *bbolt.Tx, link *domain.Link) error {
+	stored, err := findLink(tx, link.Hash)
+	if err != nil {
+		return err
+	}
+
+	if stored == nil {
+		return util.NewErrorf(util.ErrCodeNotFound, "url not found")
+	}
+
+	if stored.Version != link.Version {
+		return util.NewErrorf(util.ErrCodePreconditionFailed, "url was modified, current version is %d", stored.Version)
+	}
+
+	return nil
+}
+
+func updateLink(tx *bbolt.Tx, hash string, change func(stored *domain.Link)) error {
+	stored, err := findLink(tx, hash)
+	if err != nil || stored == nil {
+		return err
+	}
+
+	change(stored)
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "json marshal error")
+	}
+
+	if err := tx.Bucket(linksBucket).Put([]byte(hash), data); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error updating url")
+	}
+
+	return nil
+}
+
+func findLink(tx *bbolt.Tx, hash string) (*domain.Link, error) {
+	data := tx.Bucket(linksBucket).Get([]byte(hash))
+	if data == nil {
+		return nil, nil
+	}
+
+	var link domain.Link
+	if err := json.Unmarshal(data, &link); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
+	}
+
+	return &link, nil
+}
@@ -0,0 +1,28 @@
+package bolt
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hugosrc/shortlink/internal/core/port/porttest"
+	"github.com/spf13/viper"
+)
+
+func TestRepositories(t *testing.T) {
+	conf := viper.New()
+	conf.Set("BOLT_PATH", filepath.Join(t.TempDir(), "shortlink.db"))
+
+	db, err := New(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	porttest.Run(t, porttest.Repositories{
+		Links:      NewLinkRepository(db),
+		Workspaces: NewWorkspaceRepository(db),
+		APIKeys:    NewAPIKeyRepository(db),
+		Audit:      NewAuditRepository(db),
+		Search:     NewLinkIndex(db),
+	})
+}
@@ -0,0 +1,123 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+	"go.etcd.io/bbolt"
+)
+
+// LinkIndex keys the searchable attributes of the links by owner and
+// creation time, so the links of an owner are scanned newest first and
+// filtered as they are read.
+type LinkIndex struct {
+	db *bbolt.DB
+}
+
+func NewLinkIndex(db *bbolt.DB) port.LinkIndex {
+	return &LinkIndex{
+		db: db,
+	}
+}
+
+func (i *LinkIndex) Index(ctx context.Context, link *domain.Link) error {
+	data, err := json.Marshal(&domain.Link{
+		Hash:         link.Hash,
+		OriginalURL:  link.OriginalURL,
+		Title:        link.Title,
+		Tags:         link.Tags,
+		CreationTime: link.CreationTime,
+	})
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "json marshal error")
+	}
+
+	if err := i.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(linksByOwnerIndex).Put(ownerKey(link), data)
+	}); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error indexing url")
+	}
+
+	return nil
+}
+
+func (i *LinkIndex) Remove(ctx context.Context, link *domain.Link) error {
+	if err := i.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(linksByOwnerIndex).Delete(ownerKey(link))
+	}); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error removing url from index")
+	}
+
+	return nil
+}
+
+func (i *LinkIndex) Clear(ctx context.Context, owner string) error {
+	if err := i.db.Update(func(tx *bbolt.Tx) error {
+		prefix := keyPrefix(owner)
+		c := tx.Bucket(linksByOwnerIndex).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error clearing url index")
+	}
+
+	return nil
+}
+
+// Search walks the links of the owner backwards, from the end of the period
+// or from the last link created.
+func (i *LinkIndex) Search(ctx context.Context, query *domain.LinkQuery) ([]string, error) {
+	hashes := []string{}
+
+	if err := i.db.View(func(tx *bbolt.Tx) error {
+		prefix := keyPrefix(query.Owner())
+		c := tx.Bucket(linksByOwnerIndex).Cursor()
+
+		// the first key after the owner's links, or after the period
+		end := []byte(query.Owner() + "\x01")
+		if !query.Until.IsZero() {
+			end = append(keyPrefix(query.Owner()), encodeUint(uint64(query.Until.UnixNano()))...)
+		}
+
+		k, data := c.Seek(end)
+		if k == nil {
+			k, data = c.Last()
+		} else {
+			k, data = c.Prev()
+		}
+
+		for ; k != nil && bytes.HasPrefix(k, prefix) && len(hashes) < query.Limit; k, data = c.Prev() {
+			var link domain.Link
+			if err := json.Unmarshal(data, &link); err != nil {
+				return err
+			}
+
+			if !query.Since.IsZero() && link.CreationTime.Before(query.Since) {
+				break
+			}
+
+			if query.Matches(&link) {
+				hashes = append(hashes, link.Hash)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error searching urls")
+	}
+
+	return hashes, nil
+}
+
+func ownerKey(link *domain.Link) []byte {
+	return compositeKey(domain.LinkOwner(link), string(encodeUint(uint64(link.CreationTime.UnixNano())))+link.Hash)
+}
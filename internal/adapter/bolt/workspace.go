@@ -0,0 +1,168 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+	"go.etcd.io/bbolt"
+)
+
+// WorkspaceRepository keeps members keyed by workspace and user, and an
+// index of the workspaces of each user.
+type WorkspaceRepository struct {
+	db *bbolt.DB
+}
+
+func NewWorkspaceRepository(db *bbolt.DB) port.WorkspaceRepository {
+	return &WorkspaceRepository{
+		db: db,
+	}
+}
+
+func (r *WorkspaceRepository) Create(ctx context.Context, workspace *domain.Workspace, owner *domain.Member) error {
+	data, err := json.Marshal(workspace)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "json marshal error")
+	}
+
+	if err := r.db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(workspacesBucket).Put([]byte(workspace.ID), data); err != nil {
+			return err
+		}
+
+		return putMember(tx, owner)
+	}); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting workspace")
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) FindByID(ctx context.Context, id string) (*domain.Workspace, error) {
+	var data []byte
+	_ = r.db.View(func(tx *bbolt.Tx) error {
+		data = copyBytes(tx.Bucket(workspacesBucket).Get([]byte(id)))
+		return nil
+	})
+
+	if data == nil {
+		return nil, util.NewErrorf(util.ErrCodeNotFound, "workspace not found")
+	}
+
+	var workspace domain.Workspace
+	if err := json.Unmarshal(data, &workspace); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
+	}
+
+	return &workspace, nil
+}
+
+func (r *WorkspaceRepository) FindMember(ctx context.Context, workspaceID string, userID string) (*domain.Member, error) {
+	var data []byte
+	_ = r.db.View(func(tx *bbolt.Tx) error {
+		data = copyBytes(tx.Bucket(workspaceMembersIndex).Get(compositeKey(workspaceID, userID)))
+		return nil
+	})
+
+	if data == nil {
+		return nil, util.NewErrorf(util.ErrCodeNotFound, "member not found")
+	}
+
+	var member domain.Member
+	if err := json.Unmarshal(data, &member); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
+	}
+
+	return &member, nil
+}
+
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID string) ([]*domain.Member, error) {
+	members, err := r.listMembers(workspaceMembersIndex, workspaceID)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing members")
+	}
+
+	return members, nil
+}
+
+func (r *WorkspaceRepository) ListByUser(ctx context.Context, userID string) ([]*domain.Member, error) {
+	members, err := r.listMembers(workspacesByUserIndex, userID)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing workspaces")
+	}
+
+	return members, nil
+}
+
+// listMembers returns the members stored in bucket under the given first
+// key part, in key order.
+func (r *WorkspaceRepository) listMembers(bucket []byte, first string) ([]*domain.Member, error) {
+	var members []*domain.Member
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		prefix := keyPrefix(first)
+		c := tx.Bucket(bucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var member domain.Member
+			if err := json.Unmarshal(v, &member); err != nil {
+				return err
+			}
+
+			members = append(members, &member)
+		}
+
+		return nil
+	})
+
+	return members, err
+}
+
+func (r *WorkspaceRepository) SaveMember(ctx context.Context, member *domain.Member) error {
+	if err := r.db.Update(func(tx *bbolt.Tx) error {
+		return putMember(tx, member)
+	}); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error saving member")
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) DeleteMember(ctx context.Context, workspaceID string, userID string) error {
+	if err := r.db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(workspaceMembersIndex).Delete(compositeKey(workspaceID, userID)); err != nil {
+			return err
+		}
+
+		return tx.Bucket(workspacesByUserIndex).Delete(compositeKey(userID, workspaceID))
+	}); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting member")
+	}
+
+	return nil
+}
+
+func putMember(tx *bbolt.Tx, member *domain.Member) error {
+	data, err := json.Marshal(member)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(workspaceMembersIndex).Put(compositeKey(member.WorkspaceID, member.UserID), data); err != nil {
+		return err
+	}
+
+	return tx.Bucket(workspacesByUserIndex).Put(compositeKey(member.UserID, member.WorkspaceID), data)
+}
+
+// copyBytes copies values read from the database, which are only valid
+// during the transaction.
+func copyBytes(data []byte) []byte {
+	if data == nil {
+		return nil
+	}
+
+	return append([]byte(nil), data...)
+}
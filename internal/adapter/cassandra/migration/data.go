@@ -0,0 +1,43 @@
+package migration
+
+import (
+	"context"
+
+	"github.com/gocql/gocql"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// copyOwnerIDs copies the uuid user_id of the links into owner_id. The
+// update is conditioned on owner_id being null, so links changed by a
+// newer server in the meantime are left alone and an interrupted copy can
+// run again, and on original_url so deleted links aren't recreated.
+func copyOwnerIDs(ctx context.Context, session *gocql.Session) error {
+	iter := session.Query("SELECT hash, user_id, owner_id FROM url_mapping;").WithContext(ctx).Iter()
+
+	var (
+		hash    string
+		userID  string
+		ownerID string
+	)
+
+	for iter.Scan(&hash, &userID, &ownerID) {
+		if len(userID) == 0 || len(ownerID) > 0 {
+			continue
+		}
+
+		if _, err := session.Query(
+			"UPDATE url_mapping SET owner_id = ? WHERE hash = ? IF owner_id = null AND original_url != null;",
+			userID,
+			hash,
+		).WithContext(ctx).MapScanCAS(map[string]interface{}{}); err != nil {
+			_ = iter.Close()
+			return util.WrapErrorf(err, util.ErrCodeUnknown, "error copying owner of url %s", hash)
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error copying url owners")
+	}
+
+	return nil
+}
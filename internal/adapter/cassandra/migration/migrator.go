@@ -0,0 +1,428 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/spf13/viper"
+)
+
+//go:embed migrations/*.cql
+var files embed.FS
+
+const (
+	lockID             = "schema"
+	lockTTL            = 5 * time.Minute
+	lockRenewInterval  = lockTTL / 5
+	lockRetryInterval  = 2 * time.Second
+	lockReleaseTimeout = 10 * time.Second
+
+	defaultReplication = "{'class': 'SimpleStrategy', 'replication_factor': 1}"
+)
+
+// Migration is a versioned CQL file. Files are named <version>_<name>.cql
+// and are applied in version order.
+type Migration struct {
+	Version    int
+	Name       string
+	Checksum   string
+	Statements []string
+
+	// Data runs after the statements, for changes CQL can't express such
+	// as copying between columns.
+	Data func(ctx context.Context, session *gocql.Session) error
+}
+
+// dataMigrations are the data steps of the migrations, by version.
+var dataMigrations = map[int]func(ctx context.Context, session *gocql.Session) error{
+	9: copyOwnerIDs,
+}
+
+// Migrator applies the embedded migrations to the keyspace of the cluster
+// and records them in the schema_migrations table. Replicas starting at the
+// same time take turns through a lock acquired with a lightweight
+// transaction.
+type Migrator struct {
+	cluster     *gocql.ClusterConfig
+	replication string
+	migrations  []Migration
+}
+
+// New loads the embedded migrations. The keyspace is created, if needed,
+// with the replication of CASSANDRA_REPLICATION.
+func New(cluster *gocql.ClusterConfig, conf *viper.Viper) (*Migrator, error) {
+	migrations, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	replication := conf.GetString("CASSANDRA_REPLICATION")
+	if len(replication) == 0 {
+		replication = defaultReplication
+	}
+
+	return &Migrator{
+		cluster:     cluster,
+		replication: replication,
+		migrations:  migrations,
+	}, nil
+}
+
+func load() ([]Migration, error) {
+	entries, err := files.ReadDir("migrations")
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error reading migrations")
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".cql")
+
+		parts := strings.SplitN(name, "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) != 2 {
+			return nil, util.NewErrorf(util.ErrCodeUnknown, "migration %s is not named <version>_<name>.cql", entry.Name())
+		}
+
+		data, err := files.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error reading migration %s", entry.Name())
+		}
+
+		sum := sha256.Sum256(data)
+		migrations = append(migrations, Migration{
+			Version:    version,
+			Name:       parts[1],
+			Checksum:   hex.EncodeToString(sum[:]),
+			Statements: statements(string(data)),
+			Data:       dataMigrations[version],
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, util.NewErrorf(util.ErrCodeUnknown, "duplicate migration version %d", migrations[i].Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// statements splits a file on semicolons, dropping "--" comment lines.
+func statements(cql string) []string {
+	var lines []string
+	for _, line := range strings.Split(cql, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	var result []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); len(stmt) > 0 {
+			result = append(result, stmt)
+		}
+	}
+
+	return result
+}
+
+// Up creates the keyspace if needed, applies the pending migrations and
+// returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.createKeyspace(ctx); err != nil {
+		return nil, err
+	}
+
+	session, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	if err := bootstrap(ctx, session); err != nil {
+		return nil, err
+	}
+
+	held, release, err := lock(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	done, err := m.up(held, session)
+	if err != nil && held.Err() != nil && ctx.Err() == nil {
+		return done, util.WrapErrorf(err, util.ErrCodeUnknown, "migration lock lost")
+	}
+
+	return done, err
+}
+
+func (m *Migrator) up(ctx context.Context, session *gocql.Session) ([]Migration, error) {
+	applied, err := appliedMigrations(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if checksum, ok := applied[migration.Version]; ok {
+			if checksum != migration.Checksum {
+				return done, util.NewErrorf(util.ErrCodeUnknown, "migration %d was changed after being applied", migration.Version)
+			}
+			continue
+		}
+
+		if err := apply(ctx, session, migration); err != nil {
+			return done, err
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Verify checks that every migration has been applied unchanged and that
+// the schema isn't ahead of this build.
+func (m *Migrator) Verify(ctx context.Context) error {
+	session, err := m.connect()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	applied, err := appliedMigrations(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, migration := range m.migrations {
+		checksum, ok := applied[migration.Version]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%d_%s is pending", migration.Version, migration.Name))
+		case checksum != migration.Checksum:
+			problems = append(problems, fmt.Sprintf("%d_%s was changed after being applied", migration.Version, migration.Name))
+		}
+		delete(applied, migration.Version)
+	}
+
+	for version := range applied {
+		problems = append(problems, fmt.Sprintf("%d is applied but unknown to this build", version))
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return util.NewErrorf(util.ErrCodeUnknown, "schema is out of date: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// createKeyspace runs without a keyspace, since sessions can't be opened
+// on one that doesn't exist yet.
+func (m *Migrator) createKeyspace(ctx context.Context) error {
+	cluster := *m.cluster
+	cluster.Keyspace = ""
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error connecting to cassandra server")
+	}
+	defer session.Close()
+
+	return exec(ctx, session, fmt.Sprintf("CREATE KEYSPACE IF NOT EXISTS %q WITH REPLICATION = %s", m.cluster.Keyspace, m.replication))
+}
+
+func (m *Migrator) connect() (*gocql.Session, error) {
+	session, err := m.cluster.CreateSession()
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error connecting to cassandra keyspace %s", m.cluster.Keyspace)
+	}
+
+	return session, nil
+}
+
+// bootstrap creates the tables tracking the migrations.
+func bootstrap(ctx context.Context, session *gocql.Session) error {
+	for _, stmt := range []string{
+		"CREATE TABLE IF NOT EXISTS schema_migrations (version INT, name VARCHAR, checksum VARCHAR, applied_at TIMESTAMP, PRIMARY KEY (version))",
+		"CREATE TABLE IF NOT EXISTS schema_migrations_lock (id VARCHAR, owner VARCHAR, acquired_at TIMESTAMP, PRIMARY KEY (id))",
+	} {
+		if err := exec(ctx, session, stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lock waits until this process holds the migration lock. The lock expires
+// on its own if the holder dies before releasing it, so it is renewed while
+// held; the returned context is canceled if a renewal fails, since another
+// process may have taken over.
+func lock(ctx context.Context, session *gocql.Session) (context.Context, func(), error) {
+	owner, err := util.NewUUID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		owner = hostname + "/" + owner
+	}
+
+	var acquiredAt time.Time
+	for {
+		acquiredAt = time.Now()
+		acquired, err := session.Query(
+			"INSERT INTO schema_migrations_lock (id, owner, acquired_at) VALUES (?, ?, ?) IF NOT EXISTS USING TTL ?;",
+			lockID,
+			owner,
+			acquiredAt,
+			int(lockTTL.Seconds()),
+		).WithContext(ctx).MapScanCAS(map[string]interface{}{})
+		if err != nil {
+			return nil, nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error acquiring migration lock")
+		}
+
+		if acquired {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, util.WrapErrorf(ctx.Err(), util.ErrCodeUnknown, "migration lock held by another process")
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	held, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(lockRenewInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-held.Done():
+				return
+			case <-ticker.C:
+			}
+
+			renewed, err := session.Query(
+				"UPDATE schema_migrations_lock USING TTL ? SET owner = ?, acquired_at = ? WHERE id = ? IF owner = ?;",
+				int(lockTTL.Seconds()),
+				owner,
+				acquiredAt,
+				lockID,
+				owner,
+			).WithContext(held).MapScanCAS(map[string]interface{}{})
+			if err != nil || !renewed {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return held, func() {
+		cancel()
+		<-stopped
+
+		ctx, stop := context.WithTimeout(context.Background(), lockReleaseTimeout)
+		defer stop()
+
+		_, _ = session.Query(
+			"DELETE FROM schema_migrations_lock WHERE id = ? IF owner = ?;",
+			lockID,
+			owner,
+		).WithContext(ctx).MapScanCAS(map[string]interface{}{})
+	}, nil
+}
+
+func appliedMigrations(ctx context.Context, session *gocql.Session) (map[int]string, error) {
+	applied := make(map[int]string)
+
+	var (
+		version  int
+		checksum string
+	)
+
+	iter := session.Query("SELECT version, checksum FROM schema_migrations;").WithContext(ctx).Iter()
+	for iter.Scan(&version, &checksum) {
+		applied[version] = checksum
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving applied migrations")
+	}
+
+	return applied, nil
+}
+
+func apply(ctx context.Context, session *gocql.Session, migration Migration) error {
+	for _, stmt := range migration.Statements {
+		if err := exec(ctx, session, stmt); err != nil {
+			return util.WrapErrorf(err, util.ErrCodeUnknown, "migration %d_%s", migration.Version, migration.Name)
+		}
+	}
+
+	if migration.Data != nil {
+		if err := migration.Data(ctx, session); err != nil {
+			return util.WrapErrorf(err, util.ErrCodeUnknown, "migration %d_%s", migration.Version, migration.Name)
+		}
+	}
+
+	if err := session.Query(
+		"INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?);",
+		migration.Version,
+		migration.Name,
+		migration.Checksum,
+		time.Now(),
+	).WithContext(ctx).Exec(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error recording migration %d", migration.Version)
+	}
+
+	return nil
+}
+
+// exec runs a schema change and waits for every node to agree on the new
+// schema before the next one.
+func exec(ctx context.Context, session *gocql.Session, stmt string) error {
+	if err := session.Query(stmt).WithContext(ctx).Exec(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error executing %q", firstLine(stmt))
+	}
+
+	if err := session.AwaitSchemaAgreement(ctx); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error awaiting schema agreement")
+	}
+
+	return nil
+}
+
+func firstLine(stmt string) string {
+	if i := strings.IndexByte(stmt, '\n'); i >= 0 {
+		return stmt[:i]
+	}
+
+	return stmt
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+type APIKeyRepository struct {
+	conn *gocql.Session
+	opts QueryOptions
+}
+
+func NewAPIKeyRepository(conn *gocql.Session, opts QueryOptions) port.APIKeyRepository {
+	return &APIKeyRepository{
+		conn: conn,
+		opts: opts,
+	}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	batch := r.opts.batch(r.conn.NewBatch(gocql.LoggedBatch)).WithContext(ctx)
+	batch.Query(
+		"INSERT INTO api_keys (id, user_id, name, hash, scopes, expires_at, creation_time) VALUES (?, ?, ?, ?, ?, ?, ?);",
+		key.ID, key.UserID, key.Name, key.Hash, key.Scopes, key.ExpiresAt, key.CreationTime,
+	)
+	batch.Query(
+		"INSERT INTO api_keys_by_user (user_id, id) VALUES (?, ?);",
+		key.UserID, key.ID,
+	)
+
+	if err := r.conn.ExecuteBatch(batch); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting api key")
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepository) FindByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := r.opts.read(r.conn.Query(
+		"SELECT id, user_id, name, hash, scopes, expires_at, last_used_at, revoked_at, creation_time FROM api_keys WHERE id = ?;", id,
+	)).WithContext(ctx).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Hash,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreationTime,
+	); err != nil {
+		if errors.Is(err, gocql.ErrNotFound) {
+			return nil, util.WrapErrorf(err, util.ErrCodeNotFound, "api key not found")
+		}
+
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving api key")
+	}
+
+	return &key, nil
+}
+
+func (r *APIKeyRepository) ListByUser(ctx context.Context, userID string) ([]*domain.APIKey, error) {
+	iter := r.opts.read(r.conn.Query(
+		"SELECT id FROM api_keys_by_user WHERE user_id = ?;", userID,
+	)).WithContext(ctx).Iter()
+
+	var (
+		ids []string
+		id  string
+	)
+
+	for iter.Scan(&id) {
+		ids = append(ids, id)
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing api keys")
+	}
+
+	keys := make([]*domain.APIKey, 0, len(ids))
+	for _, id := range ids {
+		key, err := r.FindByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	if err := r.opts.write(r.conn.Query(
+		"UPDATE api_keys SET revoked_at = ? WHERE id = ? IF EXISTS;", at, id,
+	)).WithContext(ctx).Exec(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error revoking api key")
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepository) UpdateLastUsed(ctx context.Context, id string, at time.Time) error {
+	if err := r.opts.write(r.conn.Query(
+		"UPDATE api_keys SET last_used_at = ? WHERE id = ?;", at, id,
+	)).WithContext(ctx).Exec(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error updating api key")
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const (
+	auditColumns = "id, hash, action, actor, api_key_id, old_value, new_value, request, time"
+
+	// defaultAuditWindow is how far back queries without a start look, as
+	// events partitioned by day are read one partition at a time.
+	defaultAuditWindow = 30 * 24 * time.Hour
+	maxAuditWindow     = 366 * 24 * time.Hour
+)
+
+// AuditRepository writes every event to two tables, partitioned by link and
+// by day, so the history of a link and the whole trail over a period can
+// both be read without indexes.
+type AuditRepository struct {
+	conn *gocql.Session
+	opts QueryOptions
+}
+
+func NewAuditRepository(conn *gocql.Session, opts QueryOptions) port.AuditRepository {
+	return &AuditRepository{
+		conn: conn,
+		opts: opts,
+	}
+}
+
+func (r *AuditRepository) Append(ctx context.Context, event *domain.AuditEvent) error {
+	before, err := marshalJSON(event.Before)
+	if err != nil {
+		return err
+	}
+
+	after, err := marshalJSON(event.After)
+	if err != nil {
+		return err
+	}
+
+	request, err := marshalJSON(event.Request)
+	if err != nil {
+		return err
+	}
+
+	batch := r.opts.batch(r.conn.NewBatch(gocql.LoggedBatch)).WithContext(ctx)
+	batch.Query(
+		"INSERT INTO link_audit (hash, time, id, action, actor, api_key_id, old_value, new_value, request) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);",
+		event.Hash, event.Time, event.ID, event.Action, event.Actor, event.APIKeyID, before, after, request,
+	)
+	batch.Query(
+		"INSERT INTO link_audit_by_day (day, time, id, hash, action, actor, api_key_id, old_value, new_value, request) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);",
+		day(event.Time), event.Time, event.ID, event.Hash, event.Action, event.Actor, event.APIKeyID, before, after, request,
+	)
+
+	if err := r.conn.ExecuteBatch(batch); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting audit event")
+	}
+
+	return nil
+}
+
+func (r *AuditRepository) ListByHash(ctx context.Context, hash string, limit int) ([]*domain.AuditEvent, error) {
+	return r.Query(ctx, &domain.AuditFilter{Hash: hash, Limit: limit})
+}
+
+// Query reads the history of the link when the filter has a hash, otherwise
+// it walks the daily partitions from the end of the period backwards.
+func (r *AuditRepository) Query(ctx context.Context, filter *domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	until := filter.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+
+	var events []*domain.AuditEvent
+
+	if len(filter.Hash) > 0 {
+		stmt := "SELECT " + auditColumns + " FROM link_audit WHERE hash = ? AND time < ?"
+		args := []interface{}{filter.Hash, until}
+		if !filter.Since.IsZero() {
+			stmt += " AND time >= ?"
+			args = append(args, filter.Since)
+		}
+
+		if err := r.scan(ctx, filter, &events, r.opts.read(r.conn.Query(stmt+";", args...))); err != nil {
+			return nil, err
+		}
+
+		return events, nil
+	}
+
+	since := filter.Since
+	if since.IsZero() {
+		since = until.Add(-defaultAuditWindow)
+	}
+
+	if until.Sub(since) > maxAuditWindow {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "audit queries can span at most %d days", int(maxAuditWindow.Hours()/24))
+	}
+
+	for d := day(until); !d.Before(day(since)) && len(events) < filter.Limit; d = d.AddDate(0, 0, -1) {
+		err := r.scan(ctx, filter, &events, r.opts.read(r.conn.Query(
+			"SELECT "+auditColumns+" FROM link_audit_by_day WHERE day = ? AND time >= ? AND time < ?;",
+			d, since, until,
+		)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return events, nil
+}
+
+// scan appends the events returned by the query that match the filter
+// until the limit is reached.
+func (r *AuditRepository) scan(ctx context.Context, filter *domain.AuditFilter, events *[]*domain.AuditEvent, query *gocql.Query) error {
+	iter := query.WithContext(ctx).Iter()
+
+	var (
+		event                   domain.AuditEvent
+		before, after, metadata string
+	)
+
+	for len(*events) < filter.Limit && iter.Scan(
+		&event.ID,
+		&event.Hash,
+		&event.Action,
+		&event.Actor,
+		&event.APIKeyID,
+		&before,
+		&after,
+		&metadata,
+		&event.Time,
+	) {
+		e := event
+		e.Before, e.After, e.Request = nil, nil, nil
+
+		if err := unmarshalJSON(before, &e.Before); err != nil {
+			_ = iter.Close()
+			return err
+		}
+
+		if err := unmarshalJSON(after, &e.After); err != nil {
+			_ = iter.Close()
+			return err
+		}
+
+		if err := unmarshalJSON(metadata, &e.Request); err != nil {
+			_ = iter.Close()
+			return err
+		}
+
+		if filter.Matches(&e) {
+			*events = append(*events, &e)
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error listing audit events")
+	}
+
+	return nil
+}
+
+// day truncates t to the UTC day partitioning the events.
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
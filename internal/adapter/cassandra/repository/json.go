@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// marshalJSON encodes structured link attributes stored in text columns.
+// Empty values are stored as null so unused columns don't take up space.
+func marshalJSON(v interface{}) (*string, error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || ((rv.Kind() == reflect.Slice || rv.Kind() == reflect.Ptr) && rv.IsNil()) ||
+		(rv.Kind() == reflect.Slice && rv.Len() == 0) {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "json marshal error")
+	}
+
+	value := string(data)
+	return &value, nil
+}
+
+func unmarshalJSON(data string, v interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/gocql/gocql"
 	"github.com/hugosrc/shortlink/internal/core/domain"
@@ -12,48 +13,257 @@ import (
 
 type LinkRepository struct {
 	conn *gocql.Session
+	opts QueryOptions
 }
 
-func NewLinkRepository(conn *gocql.Session) port.LinkRepository {
+func NewLinkRepository(conn *gocql.Session, opts QueryOptions) port.LinkRepository {
 	return &LinkRepository{
 		conn: conn,
+		opts: opts,
 	}
 }
 
 func (r *LinkRepository) Create(ctx context.Context, link *domain.Link) error {
-	if err := r.conn.Query(
-		"INSERT INTO shortlink.url_mapping (hash, original_url, user_id, creation_time) VALUES (?, ?, ?, ?);",
+	rules, err := marshalJSON(link.Rules)
+	if err != nil {
+		return err
+	}
+
+	variants, err := marshalJSON(link.Variants)
+	if err != nil {
+		return err
+	}
+
+	socialCard, err := marshalJSON(link.SocialCard)
+	if err != nil {
+		return err
+	}
+
+	if err := r.opts.write(r.conn.Query(
+		"INSERT INTO url_mapping (hash, original_url, owner_id, workspace_id, utm, forward_query, rules, variants, social_card, title, description, tags, notes, disabled, anonymous, expires_at, creation_time, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);",
 		link.Hash,
 		link.OriginalURL,
-		link.UserID,
+		nullableString(link.UserID),
+		link.WorkspaceID,
+		marshalUTM(link.UTM),
+		link.ForwardQuery,
+		rules,
+		variants,
+		socialCard,
+		link.Title,
+		link.Description,
+		link.Tags,
+		link.Notes,
+		link.Disabled,
+		link.Anonymous,
+		link.ExpiresAt,
 		link.CreationTime,
-	).WithContext(ctx).Exec(); err != nil {
+		link.Version,
+	)).WithContext(ctx).Exec(); err != nil {
 		return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting url")
 	}
 
 	return nil
 }
 
+// Delete removes the link permanently, along with its trash entry.
 func (r *LinkRepository) Delete(ctx context.Context, hash string) error {
-	if err := r.conn.Query(
-		"DELETE FROM shortlink.url_mapping WHERE hash = ?;",
+	var ownerID, legacyUserID string
+	if err := r.opts.read(r.conn.Query(
+		"SELECT owner_id, user_id FROM url_mapping WHERE hash = ?;", hash,
+	)).WithContext(ctx).Scan(&ownerID, &legacyUserID); err != nil {
+		if errors.Is(err, gocql.ErrNotFound) {
+			return nil
+		}
+
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting url")
+	}
+
+	batch := r.opts.batch(r.conn.NewBatch(gocql.LoggedBatch)).WithContext(ctx)
+	batch.Query(
+		"DELETE FROM url_mapping WHERE hash = ?;",
 		hash,
-	).WithContext(ctx).Exec(); err != nil {
+	)
+	if userID := linkOwnerID(ownerID, legacyUserID); len(userID) > 0 {
+		batch.Query(
+			"DELETE FROM links_trash WHERE user_id = ? AND hash = ?;",
+			userID, hash,
+		)
+	}
+
+	if err := r.conn.ExecuteBatch(batch); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting url")
+	}
+
+	return nil
+}
+
+// SoftDelete marks the link as deleted and adds it to the trash of its
+// owner. Conditional updates can't be batched with other partitions, so
+// the trash entry is only added once the link is marked.
+func (r *LinkRepository) SoftDelete(ctx context.Context, link *domain.Link, at time.Time) error {
+	if err := r.compareAndSet(ctx, link, "deleted_at = ?", at); err != nil {
+		return err
+	}
+
+	if err := r.opts.write(r.conn.Query(
+		"INSERT INTO links_trash (user_id, hash, deleted_at) VALUES (?, ?, ?);",
+		link.UserID, link.Hash, at,
+	)).WithContext(ctx).Exec(); err != nil {
 		return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting url")
 	}
 
 	return nil
 }
 
+// Restore unmarks the link before removing its trash entry. An entry left
+// behind by a failure is skipped when purging, as the link is not deleted.
+func (r *LinkRepository) Restore(ctx context.Context, link *domain.Link) error {
+	if err := r.compareAndSet(ctx, link, "deleted_at = null"); err != nil {
+		return err
+	}
+
+	if err := r.opts.write(r.conn.Query(
+		"DELETE FROM links_trash WHERE user_id = ? AND hash = ?;",
+		link.UserID, link.Hash,
+	)).WithContext(ctx).Exec(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error restoring url")
+	}
+
+	return nil
+}
+
+func (r *LinkRepository) ListDeleted(ctx context.Context, userID string) ([]*domain.Link, error) {
+	iter := r.opts.read(r.conn.Query(
+		"SELECT hash FROM links_trash WHERE user_id = ?;", userID,
+	)).WithContext(ctx).Iter()
+
+	var (
+		hashes []string
+		hash   string
+	)
+
+	for iter.Scan(&hash) {
+		hashes = append(hashes, hash)
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing deleted urls")
+	}
+
+	links := make([]*domain.Link, 0, len(hashes))
+	for _, hash := range hashes {
+		link, err := r.FindByHash(ctx, hash)
+		if err != nil {
+			var appErr *util.Error
+			if errors.As(err, &appErr) && appErr.Code() == util.ErrCodeNotFound {
+				continue
+			}
+
+			return nil, err
+		}
+
+		links = append(links, link)
+	}
+
+	return links, nil
+}
+
+// ListDeletedBefore scans the whole trash, which only holds the links
+// deleted within the retention period.
+func (r *LinkRepository) ListDeletedBefore(ctx context.Context, before time.Time) ([]*domain.Link, error) {
+	iter := r.opts.read(r.conn.Query(
+		"SELECT user_id, hash, deleted_at FROM links_trash;",
+	)).WithContext(ctx).Iter()
+
+	var (
+		links     []*domain.Link
+		userID    string
+		hash      string
+		deletedAt time.Time
+	)
+
+	for iter.Scan(&userID, &hash, &deletedAt) {
+		if deletedAt.Before(before) {
+			at := deletedAt
+			links = append(links, &domain.Link{Hash: hash, UserID: userID, DeletedAt: &at})
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing deleted urls")
+	}
+
+	return links, nil
+}
+
+// Each scans the hashes of url_mapping and reads every link by its hash.
+func (r *LinkRepository) Each(ctx context.Context, fn func(link *domain.Link) error) error {
+	iter := r.opts.read(r.conn.Query(
+		"SELECT hash FROM url_mapping;",
+	)).WithContext(ctx).Iter()
+
+	var hash string
+	for iter.Scan(&hash) {
+		link, err := r.FindByHash(ctx, hash)
+		if err != nil {
+			var appErr *util.Error
+			if errors.As(err, &appErr) && appErr.Code() == util.ErrCodeNotFound {
+				continue
+			}
+
+			_ = iter.Close()
+			return err
+		}
+
+		if err := fn(link); err != nil {
+			_ = iter.Close()
+			return err
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error listing urls")
+	}
+
+	return nil
+}
+
 func (r *LinkRepository) FindByHash(ctx context.Context, hash string) (*domain.Link, error) {
-	var link domain.Link
-	if err := r.conn.Query(
-		"SELECT hash, original_url, user_id, creation_time FROM shortlink.url_mapping WHERE hash = ?;", hash,
-	).WithContext(ctx).Consistency(gocql.One).Scan(
+	var (
+		link         domain.Link
+		legacyUserID string
+		utm          map[string]string
+		rules        string
+		variants     string
+		metadata     string
+		socialCard   string
+	)
+
+	if err := r.opts.read(r.conn.Query(
+		"SELECT hash, original_url, owner_id, user_id, workspace_id, utm, forward_query, rules, variants, metadata, social_card, title, description, tags, notes, disabled, anonymous, expires_at, deleted_at, creation_time, version FROM url_mapping WHERE hash = ?;", hash,
+	)).WithContext(ctx).Scan(
 		&link.Hash,
 		&link.OriginalURL,
 		&link.UserID,
+		&legacyUserID,
+		&link.WorkspaceID,
+		&utm,
+		&link.ForwardQuery,
+		&rules,
+		&variants,
+		&metadata,
+		&socialCard,
+		&link.Title,
+		&link.Description,
+		&link.Tags,
+		&link.Notes,
+		&link.Disabled,
+		&link.Anonymous,
+		&link.ExpiresAt,
+		&link.DeletedAt,
 		&link.CreationTime,
+		&link.Version,
 	); err != nil {
 		if errors.Is(err, gocql.ErrNotFound) {
 			return nil, util.WrapErrorf(err, util.ErrCodeNotFound, "url not found")
@@ -62,17 +272,177 @@ func (r *LinkRepository) FindByHash(ctx context.Context, hash string) (*domain.L
 		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving url")
 	}
 
+	link.UserID = linkOwnerID(link.UserID, legacyUserID)
+	link.UTM = unmarshalUTM(utm)
+	if err := unmarshalJSON(rules, &link.Rules); err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalJSON(variants, &link.Variants); err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalJSON(metadata, &link.Metadata); err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalJSON(socialCard, &link.SocialCard); err != nil {
+		return nil, err
+	}
+
 	return &link, nil
 }
 
-func (r *LinkRepository) Update(ctx context.Context, hash string, newURL string) error {
-	if err := r.conn.Query(
-		"UPDATE shortlink.url_mapping SET original_url = ? WHERE hash = ?;",
-		newURL,
-		hash,
-	).WithContext(ctx).Exec(); err != nil {
+func (r *LinkRepository) Update(ctx context.Context, link *domain.Link) error {
+	rules, err := marshalJSON(link.Rules)
+	if err != nil {
+		return err
+	}
+
+	variants, err := marshalJSON(link.Variants)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := marshalJSON(link.Metadata)
+	if err != nil {
+		return err
+	}
+
+	socialCard, err := marshalJSON(link.SocialCard)
+	if err != nil {
+		return err
+	}
+
+	return r.compareAndSet(ctx, link,
+		"original_url = ?, utm = ?, forward_query = ?, rules = ?, variants = ?, metadata = ?, social_card = ?, title = ?, description = ?, tags = ?, notes = ?, disabled = ?",
+		link.OriginalURL,
+		marshalUTM(link.UTM),
+		link.ForwardQuery,
+		rules,
+		variants,
+		metadata,
+		socialCard,
+		link.Title,
+		link.Description,
+		link.Tags,
+		link.Notes,
+		link.Disabled,
+	)
+}
+
+// compareAndSet applies the assignments with a lightweight transaction
+// conditioned on the version of the link, and increments it.
+func (r *LinkRepository) compareAndSet(ctx context.Context, link *domain.Link, assignments string, args ...interface{}) error {
+	if err := r.updateIfVersion(ctx, link, assignments+", version = ?", append(args, link.Version+1)...); err != nil {
+		return err
+	}
+
+	link.Version++
+
+	return nil
+}
+
+// updateIfVersion applies the assignments with a lightweight transaction
+// conditioned on the version of the link, leaving the version as is. The
+// condition on original_url keeps missing links from being created by the
+// update.
+func (r *LinkRepository) updateIfVersion(ctx context.Context, link *domain.Link, assignments string, args ...interface{}) error {
+	condition, conditionArgs := versionCondition(link)
+	args = append(args, link.Hash)
+
+	current := map[string]interface{}{}
+	applied, err := r.opts.write(r.conn.Query(
+		"UPDATE url_mapping SET "+assignments+" WHERE hash = ? IF "+condition+" AND original_url != null;",
+		append(args, conditionArgs...)...,
+	)).WithContext(ctx).MapScanCAS(current)
+	if err != nil {
 		return util.WrapErrorf(err, util.ErrCodeUnknown, "error updating url")
 	}
 
+	if !applied {
+		return notApplied(current)
+	}
+
+	return nil
+}
+
+func (r *LinkRepository) Purge(ctx context.Context, link *domain.Link) error {
+	condition, conditionArgs := versionCondition(link)
+
+	current := map[string]interface{}{}
+	applied, err := r.opts.write(r.conn.Query(
+		"DELETE FROM url_mapping WHERE hash = ? IF "+condition+" AND original_url != null;",
+		append([]interface{}{link.Hash}, conditionArgs...)...,
+	)).WithContext(ctx).MapScanCAS(current)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting url")
+	}
+
+	if !applied {
+		return notApplied(current)
+	}
+
+	// the trash can't be part of a conditional batch on another table, so
+	// it is cleared once the link is gone
+	if len(link.UserID) > 0 {
+		if err := r.opts.write(r.conn.Query(
+			"DELETE FROM links_trash WHERE user_id = ? AND hash = ?;",
+			link.UserID, link.Hash,
+		)).WithContext(ctx).Exec(); err != nil {
+			return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting url")
+		}
+	}
+
 	return nil
 }
+
+// versionCondition matches the version of link. Links stored before
+// versioning have no version, read as 0.
+func versionCondition(link *domain.Link) (string, []interface{}) {
+	if link.Version == 0 {
+		return "version = null", nil
+	}
+
+	return "version = ?", []interface{}{link.Version}
+}
+
+// notApplied reports why a lightweight transaction on a link didn't apply,
+// given the current values it returned.
+func notApplied(current map[string]interface{}) error {
+	if url, _ := current["original_url"].(string); len(url) == 0 {
+		return util.NewErrorf(util.ErrCodeNotFound, "url not found")
+	}
+
+	version, _ := current["version"].(int64)
+	return util.NewErrorf(util.ErrCodePreconditionFailed, "url was modified, current version is %d", version)
+}
+
+func (r *LinkRepository) UpdateMetadata(ctx context.Context, link *domain.Link, metadata *domain.LinkMetadata) error {
+	value, err := marshalJSON(metadata)
+	if err != nil {
+		return err
+	}
+
+	return r.updateIfVersion(ctx, link, "metadata = ?", value)
+}
+
+// nullableString stores anonymous links, which have no owner, with a null
+// owner_id rather than an empty one.
+func nullableString(id string) interface{} {
+	if len(id) == 0 {
+		return nil
+	}
+
+	return id
+}
+
+// linkOwnerID returns the owner of a link. Links created before owner_id
+// are read from the uuid user_id until the migration has copied them.
+func linkOwnerID(ownerID, legacyUserID string) string {
+	if len(ownerID) > 0 {
+		return ownerID
+	}
+
+	return legacyUserID
+}
@@ -0,0 +1,62 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/adapter/cassandra"
+	"github.com/hugosrc/shortlink/internal/adapter/cassandra/migration"
+	"github.com/hugosrc/shortlink/internal/adapter/cassandra/repository"
+	"github.com/hugosrc/shortlink/internal/core/port/porttest"
+	"github.com/spf13/viper"
+)
+
+// TestRepositories migrates the keyspace of CASSANDRA_KEYSPACE on the
+// cluster of CASSANDRA_HOSTS, which should be a disposable one, and runs the
+// repository checks against it. The other CASSANDRA_* settings apply.
+func TestRepositories(t *testing.T) {
+	if len(os.Getenv("CASSANDRA_HOSTS")) == 0 {
+		t.Skip("CASSANDRA_HOSTS is not set")
+	}
+
+	conf := viper.New()
+	conf.AutomaticEnv()
+
+	cluster, err := cassandra.NewCluster(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := cassandra.NewQueryOptions(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := migration.New(cluster, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	porttest.Run(t, porttest.Repositories{
+		Links:      repository.NewLinkRepository(session, opts),
+		Workspaces: repository.NewWorkspaceRepository(session, opts),
+		APIKeys:    repository.NewAPIKeyRepository(session, opts),
+		Audit:      repository.NewAuditRepository(session, opts),
+		Search:     repository.NewLinkIndex(session, opts),
+	})
+}
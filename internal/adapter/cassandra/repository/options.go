@@ -0,0 +1,30 @@
+package repository
+
+import "github.com/gocql/gocql"
+
+// QueryOptions are applied by the repositories to every query.
+type QueryOptions struct {
+	ReadConsistency  gocql.Consistency
+	WriteConsistency gocql.Consistency
+	// Speculative is used by reads, which are all idempotent. Nil disables
+	// speculative execution.
+	Speculative gocql.SpeculativeExecutionPolicy
+}
+
+func (o QueryOptions) read(q *gocql.Query) *gocql.Query {
+	q = q.Consistency(o.ReadConsistency).Idempotent(true)
+	if o.Speculative != nil {
+		q = q.SetSpeculativeExecutionPolicy(o.Speculative)
+	}
+
+	return q
+}
+
+func (o QueryOptions) write(q *gocql.Query) *gocql.Query {
+	return q.Consistency(o.WriteConsistency)
+}
+
+func (o QueryOptions) batch(b *gocql.Batch) *gocql.Batch {
+	b.SetConsistency(o.WriteConsistency)
+	return b
+}
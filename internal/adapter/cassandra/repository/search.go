@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/gocql/gocql"
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// LinkIndex denormalizes the searchable attributes of the links into a
+// table partitioned by owner and another partitioned by owner and tag, both
+// clustered by creation time, so searches read a single partition newest
+// first and filter the remaining criteria as the rows are read.
+type LinkIndex struct {
+	conn *gocql.Session
+	opts QueryOptions
+}
+
+func NewLinkIndex(conn *gocql.Session, opts QueryOptions) port.LinkIndex {
+	return &LinkIndex{
+		conn: conn,
+		opts: opts,
+	}
+}
+
+// Index upserts the rows of the link and deletes those of the tags it no
+// longer has.
+func (i *LinkIndex) Index(ctx context.Context, link *domain.Link) error {
+	owner := domain.LinkOwner(link)
+
+	previous, err := i.tags(ctx, owner, link)
+	if err != nil {
+		return err
+	}
+
+	batch := i.opts.batch(i.conn.NewBatch(gocql.LoggedBatch)).WithContext(ctx)
+	batch.Query(
+		"INSERT INTO links_by_owner (owner, creation_time, hash, original_url, title, tags) VALUES (?, ?, ?, ?, ?, ?);",
+		owner, link.CreationTime, link.Hash, link.OriginalURL, link.Title, link.Tags,
+	)
+
+	for _, tag := range previous {
+		if !contains(link.Tags, tag) {
+			batch.Query(
+				"DELETE FROM links_by_tag WHERE owner = ? AND tag = ? AND creation_time = ? AND hash = ?;",
+				owner, tag, link.CreationTime, link.Hash,
+			)
+		}
+	}
+
+	for _, tag := range link.Tags {
+		batch.Query(
+			"INSERT INTO links_by_tag (owner, tag, creation_time, hash, original_url, title, tags) VALUES (?, ?, ?, ?, ?, ?, ?);",
+			owner, tag, link.CreationTime, link.Hash, link.OriginalURL, link.Title, link.Tags,
+		)
+	}
+
+	if err := i.conn.ExecuteBatch(batch); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error indexing url")
+	}
+
+	return nil
+}
+
+func (i *LinkIndex) Remove(ctx context.Context, link *domain.Link) error {
+	owner := domain.LinkOwner(link)
+
+	previous, err := i.tags(ctx, owner, link)
+	if err != nil {
+		return err
+	}
+
+	batch := i.opts.batch(i.conn.NewBatch(gocql.LoggedBatch)).WithContext(ctx)
+	batch.Query(
+		"DELETE FROM links_by_owner WHERE owner = ? AND creation_time = ? AND hash = ?;",
+		owner, link.CreationTime, link.Hash,
+	)
+
+	for _, tag := range previous {
+		batch.Query(
+			"DELETE FROM links_by_tag WHERE owner = ? AND tag = ? AND creation_time = ? AND hash = ?;",
+			owner, tag, link.CreationTime, link.Hash,
+		)
+	}
+
+	if err := i.conn.ExecuteBatch(batch); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error removing url from index")
+	}
+
+	return nil
+}
+
+// Clear deletes the partition of the owner and those of the tags found in
+// it.
+func (i *LinkIndex) Clear(ctx context.Context, owner string) error {
+	iter := i.opts.read(i.conn.Query(
+		"SELECT tags FROM links_by_owner WHERE owner = ?;", owner,
+	)).WithContext(ctx).Iter()
+
+	var (
+		tags    []string
+		seen    = map[string]bool{}
+		allTags []string
+	)
+
+	for iter.Scan(&tags) {
+		for _, tag := range tags {
+			if !seen[tag] {
+				seen[tag] = true
+				allTags = append(allTags, tag)
+			}
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error clearing url index")
+	}
+
+	for _, tag := range allTags {
+		if err := i.opts.write(i.conn.Query(
+			"DELETE FROM links_by_tag WHERE owner = ? AND tag = ?;", owner, tag,
+		)).WithContext(ctx).Exec(); err != nil {
+			return util.WrapErrorf(err, util.ErrCodeUnknown, "error clearing url index")
+		}
+	}
+
+	if err := i.opts.write(i.conn.Query(
+		"DELETE FROM links_by_owner WHERE owner = ?;", owner,
+	)).WithContext(ctx).Exec(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error clearing url index")
+	}
+
+	return nil
+}
+
+// Search reads the partition of the first tag of the query, or the one of
+// the owner when there are no tags.
+func (i *LinkIndex) Search(ctx context.Context, query *domain.LinkQuery) ([]string, error) {
+	stmt := "SELECT hash, original_url, title, tags, creation_time FROM links_by_owner WHERE owner = ?"
+	args := []interface{}{query.Owner()}
+	if len(query.Tags) > 0 {
+		stmt = "SELECT hash, original_url, title, tags, creation_time FROM links_by_tag WHERE owner = ? AND tag = ?"
+		args = append(args, query.Tags[0])
+	}
+
+	if !query.Since.IsZero() {
+		stmt += " AND creation_time >= ?"
+		args = append(args, query.Since)
+	}
+
+	if !query.Until.IsZero() {
+		stmt += " AND creation_time < ?"
+		args = append(args, query.Until)
+	}
+
+	iter := i.opts.read(i.conn.Query(stmt+";", args...)).WithContext(ctx).Iter()
+
+	var (
+		link   domain.Link
+		hashes = []string{}
+	)
+
+	for len(hashes) < query.Limit && iter.Scan(&link.Hash, &link.OriginalURL, &link.Title, &link.Tags, &link.CreationTime) {
+		if query.Matches(&link) {
+			hashes = append(hashes, link.Hash)
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error searching urls")
+	}
+
+	return hashes, nil
+}
+
+// tags returns the tags the link was last indexed with.
+func (i *LinkIndex) tags(ctx context.Context, owner string, link *domain.Link) ([]string, error) {
+	var tags []string
+	if err := i.opts.read(i.conn.Query(
+		"SELECT tags FROM links_by_owner WHERE owner = ? AND creation_time = ? AND hash = ?;",
+		owner, link.CreationTime, link.Hash,
+	)).WithContext(ctx).Scan(&tags); err != nil && err != gocql.ErrNotFound {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error finding indexed url")
+	}
+
+	return tags, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
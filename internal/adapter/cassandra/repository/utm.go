@@ -0,0 +1,31 @@
+package repository
+
+import "github.com/hugosrc/shortlink/internal/core/domain"
+
+func marshalUTM(utm *domain.UTM) map[string]string {
+	if utm == nil {
+		return nil
+	}
+
+	return map[string]string{
+		"source":   utm.Source,
+		"medium":   utm.Medium,
+		"campaign": utm.Campaign,
+		"term":     utm.Term,
+		"content":  utm.Content,
+	}
+}
+
+func unmarshalUTM(values map[string]string) *domain.UTM {
+	if len(values) == 0 {
+		return nil
+	}
+
+	return &domain.UTM{
+		Source:   values["source"],
+		Medium:   values["medium"],
+		Campaign: values["campaign"],
+		Term:     values["term"],
+		Content:  values["content"],
+	}
+}
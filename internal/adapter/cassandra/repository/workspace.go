@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gocql/gocql"
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// WorkspaceRepository keeps memberships in two tables, partitioned by
+// workspace and by user, so both sides can be listed without indexes.
+type WorkspaceRepository struct {
+	conn *gocql.Session
+	opts QueryOptions
+}
+
+func NewWorkspaceRepository(conn *gocql.Session, opts QueryOptions) port.WorkspaceRepository {
+	return &WorkspaceRepository{
+		conn: conn,
+		opts: opts,
+	}
+}
+
+func (r *WorkspaceRepository) Create(ctx context.Context, workspace *domain.Workspace, owner *domain.Member) error {
+	if err := r.opts.write(r.conn.Query(
+		"INSERT INTO workspaces (id, name, created_by, creation_time) VALUES (?, ?, ?, ?);",
+		workspace.ID,
+		workspace.Name,
+		workspace.CreatedBy,
+		workspace.CreationTime,
+	)).WithContext(ctx).Exec(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting workspace")
+	}
+
+	return r.SaveMember(ctx, owner)
+}
+
+func (r *WorkspaceRepository) FindByID(ctx context.Context, id string) (*domain.Workspace, error) {
+	var workspace domain.Workspace
+	if err := r.opts.read(r.conn.Query(
+		"SELECT id, name, created_by, creation_time FROM workspaces WHERE id = ?;", id,
+	)).WithContext(ctx).Scan(
+		&workspace.ID,
+		&workspace.Name,
+		&workspace.CreatedBy,
+		&workspace.CreationTime,
+	); err != nil {
+		if errors.Is(err, gocql.ErrNotFound) {
+			return nil, util.WrapErrorf(err, util.ErrCodeNotFound, "workspace not found")
+		}
+
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving workspace")
+	}
+
+	return &workspace, nil
+}
+
+func (r *WorkspaceRepository) FindMember(ctx context.Context, workspaceID string, userID string) (*domain.Member, error) {
+	member := domain.Member{WorkspaceID: workspaceID, UserID: userID}
+	if err := r.opts.read(r.conn.Query(
+		"SELECT role, joined_at FROM workspace_members WHERE workspace_id = ? AND user_id = ?;",
+		workspaceID,
+		userID,
+	)).WithContext(ctx).Scan(
+		&member.Role,
+		&member.JoinedAt,
+	); err != nil {
+		if errors.Is(err, gocql.ErrNotFound) {
+			return nil, util.WrapErrorf(err, util.ErrCodeNotFound, "member not found")
+		}
+
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving member")
+	}
+
+	return &member, nil
+}
+
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID string) ([]*domain.Member, error) {
+	iter := r.opts.read(r.conn.Query(
+		"SELECT user_id, role, joined_at FROM workspace_members WHERE workspace_id = ?;", workspaceID,
+	)).WithContext(ctx).Iter()
+
+	var (
+		members []*domain.Member
+		member  = domain.Member{WorkspaceID: workspaceID}
+	)
+
+	for iter.Scan(&member.UserID, &member.Role, &member.JoinedAt) {
+		m := member
+		members = append(members, &m)
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing members")
+	}
+
+	return members, nil
+}
+
+func (r *WorkspaceRepository) ListByUser(ctx context.Context, userID string) ([]*domain.Member, error) {
+	iter := r.opts.read(r.conn.Query(
+		"SELECT workspace_id, role, joined_at FROM workspaces_by_user WHERE user_id = ?;", userID,
+	)).WithContext(ctx).Iter()
+
+	var (
+		members []*domain.Member
+		member  = domain.Member{UserID: userID}
+	)
+
+	for iter.Scan(&member.WorkspaceID, &member.Role, &member.JoinedAt) {
+		m := member
+		members = append(members, &m)
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing workspaces")
+	}
+
+	return members, nil
+}
+
+func (r *WorkspaceRepository) SaveMember(ctx context.Context, member *domain.Member) error {
+	batch := r.opts.batch(r.conn.NewBatch(gocql.LoggedBatch)).WithContext(ctx)
+	batch.Query(
+		"INSERT INTO workspace_members (workspace_id, user_id, role, joined_at) VALUES (?, ?, ?, ?);",
+		member.WorkspaceID, member.UserID, member.Role, member.JoinedAt,
+	)
+	batch.Query(
+		"INSERT INTO workspaces_by_user (user_id, workspace_id, role, joined_at) VALUES (?, ?, ?, ?);",
+		member.UserID, member.WorkspaceID, member.Role, member.JoinedAt,
+	)
+
+	if err := r.conn.ExecuteBatch(batch); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error saving member")
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) DeleteMember(ctx context.Context, workspaceID string, userID string) error {
+	batch := r.opts.batch(r.conn.NewBatch(gocql.LoggedBatch)).WithContext(ctx)
+	batch.Query(
+		"DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ?;",
+		workspaceID, userID,
+	)
+	batch.Query(
+		"DELETE FROM workspaces_by_user WHERE user_id = ? AND workspace_id = ?;",
+		userID, workspaceID,
+	)
+
+	if err := r.conn.ExecuteBatch(batch); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting member")
+	}
+
+	return nil
+}
@@ -1,16 +1,22 @@
 package cassandra
 
 import (
+	"strings"
+	"time"
+
 	"github.com/gocql/gocql"
+	"github.com/hugosrc/shortlink/internal/adapter/cassandra/repository"
 	"github.com/hugosrc/shortlink/internal/util"
 	"github.com/spf13/viper"
 )
 
+const defaultKeyspace = "shortlink"
+
+// New connects to the cluster configured by NewCluster.
 func New(conf *viper.Viper) (*gocql.Session, error) {
-	cluster := gocql.NewCluster(conf.GetString("CASSANDRA_SERVER"))
-	cluster.Authenticator = gocql.PasswordAuthenticator{
-		Username: conf.GetString("CASSANDRA_USER"),
-		Password: conf.GetString("CASSANDRA_PASSWORD"),
+	cluster, err := NewCluster(conf)
+	if err != nil {
+		return nil, err
 	}
 
 	session, err := cluster.CreateSession()
@@ -20,3 +26,114 @@ func New(conf *viper.Viper) (*gocql.Session, error) {
 
 	return session, nil
 }
+
+// NewCluster reads the cluster configuration. Contact points are listed in
+// CASSANDRA_HOSTS, falling back to the single CASSANDRA_SERVER. Queries are
+// routed to a replica of the partition, in CASSANDRA_LOCAL_DC when set, and
+// failed queries are retried with exponential backoff.
+func NewCluster(conf *viper.Viper) (*gocql.ClusterConfig, error) {
+	hosts := splitList(conf.GetString("CASSANDRA_HOSTS"))
+	if len(hosts) == 0 {
+		hosts = splitList(conf.GetString("CASSANDRA_SERVER"))
+	}
+
+	cluster := gocql.NewCluster(hosts...)
+	cluster.Keyspace = conf.GetString("CASSANDRA_KEYSPACE")
+	if len(cluster.Keyspace) == 0 {
+		cluster.Keyspace = defaultKeyspace
+	}
+
+	cluster.Authenticator = gocql.PasswordAuthenticator{
+		Username: conf.GetString("CASSANDRA_USER"),
+		Password: conf.GetString("CASSANDRA_PASSWORD"),
+	}
+
+	if localDC := conf.GetString("CASSANDRA_LOCAL_DC"); len(localDC) > 0 {
+		cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.DCAwareRoundRobinPolicy(localDC))
+	} else {
+		cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.RoundRobinHostPolicy())
+	}
+
+	if timeout := conf.GetDuration("CASSANDRA_TIMEOUT"); timeout > 0 {
+		cluster.Timeout = timeout
+	}
+
+	if timeout := conf.GetDuration("CASSANDRA_CONNECT_TIMEOUT"); timeout > 0 {
+		cluster.ConnectTimeout = timeout
+	}
+
+	if retries := conf.GetInt("CASSANDRA_RETRIES"); retries > 0 {
+		cluster.RetryPolicy = &gocql.ExponentialBackoffRetryPolicy{
+			NumRetries: retries,
+			Min:        durationOr(conf.GetDuration("CASSANDRA_RETRY_MIN_BACKOFF"), 100*time.Millisecond),
+			Max:        durationOr(conf.GetDuration("CASSANDRA_RETRY_MAX_BACKOFF"), time.Second),
+		}
+	}
+
+	if value := conf.GetString("CASSANDRA_SERIAL_CONSISTENCY"); len(value) > 0 {
+		if err := cluster.SerialConsistency.UnmarshalText([]byte(strings.ToUpper(value))); err != nil {
+			return nil, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "invalid CASSANDRA_SERIAL_CONSISTENCY")
+		}
+	}
+
+	if conf.GetBool("CASSANDRA_TLS_ENABLED") {
+		cluster.SslOpts = &gocql.SslOptions{
+			CaPath:                 conf.GetString("CASSANDRA_TLS_CA_FILE"),
+			CertPath:               conf.GetString("CASSANDRA_TLS_CERT_FILE"),
+			KeyPath:                conf.GetString("CASSANDRA_TLS_KEY_FILE"),
+			EnableHostVerification: !conf.GetBool("CASSANDRA_TLS_INSECURE_SKIP_VERIFY"),
+		}
+	}
+
+	return cluster, nil
+}
+
+// NewQueryOptions reads the consistency levels of reads and writes, which
+// default to ONE and QUORUM, and the speculative execution of reads, which
+// sends CASSANDRA_SPECULATIVE_ATTEMPTS extra requests to other replicas
+// when one takes longer than CASSANDRA_SPECULATIVE_DELAY.
+func NewQueryOptions(conf *viper.Viper) (repository.QueryOptions, error) {
+	opts := repository.QueryOptions{
+		ReadConsistency:  gocql.One,
+		WriteConsistency: gocql.Quorum,
+	}
+
+	if value := conf.GetString("CASSANDRA_READ_CONSISTENCY"); len(value) > 0 {
+		consistency, err := gocql.ParseConsistencyWrapper(value)
+		if err != nil {
+			return opts, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "invalid CASSANDRA_READ_CONSISTENCY")
+		}
+		opts.ReadConsistency = consistency
+	}
+
+	if value := conf.GetString("CASSANDRA_WRITE_CONSISTENCY"); len(value) > 0 {
+		consistency, err := gocql.ParseConsistencyWrapper(value)
+		if err != nil {
+			return opts, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "invalid CASSANDRA_WRITE_CONSISTENCY")
+		}
+		opts.WriteConsistency = consistency
+	}
+
+	if attempts := conf.GetInt("CASSANDRA_SPECULATIVE_ATTEMPTS"); attempts > 0 {
+		opts.Speculative = &gocql.SimpleSpeculativeExecution{
+			NumAttempts:  attempts,
+			TimeoutDelay: durationOr(conf.GetDuration("CASSANDRA_SPECULATIVE_DELAY"), 100*time.Millisecond),
+		}
+	}
+
+	return opts, nil
+}
+
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+func durationOr(value time.Duration, fallback time.Duration) time.Duration {
+	if value > 0 {
+		return value
+	}
+
+	return fallback
+}
@@ -0,0 +1,48 @@
+package file
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// MetricsProducer appends the metrics of each redirect to a file as a JSON
+// line, the same message the Kafka producer publishes.
+type MetricsProducer struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewMetricsProducer(path string) (*MetricsProducer, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error opening metrics file")
+	}
+
+	return &MetricsProducer{
+		file: file,
+	}, nil
+}
+
+func (p *MetricsProducer) Produce(metrics *domain.LinkMetrics) error {
+	data, err := json.Marshal(metrics)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "unable to marshal metrics data")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.file.Write(append(data, '\n')); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "couldn't write metrics")
+	}
+
+	return nil
+}
+
+func (p *MetricsProducer) Close() error {
+	return p.file.Close()
+}
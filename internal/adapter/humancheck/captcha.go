@@ -0,0 +1,74 @@
+package humancheck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/spf13/viper"
+)
+
+// CaptchaVerifier checks CAPTCHA responses with the provider's siteverify
+// endpoint. reCAPTCHA, hCaptcha and Turnstile share the same protocol, so
+// the provider is picked with CAPTCHA_VERIFY_URL.
+type CaptchaVerifier struct {
+	client    *http.Client
+	verifyURL string
+	secret    string
+}
+
+func NewCaptchaVerifier(config *viper.Viper) *CaptchaVerifier {
+	return &CaptchaVerifier{
+		client:    &http.Client{Timeout: 5 * time.Second},
+		verifyURL: config.GetString("CAPTCHA_VERIFY_URL"),
+		secret:    config.GetString("CAPTCHA_SECRET"),
+	}
+}
+
+func (v *CaptchaVerifier) Verify(ctx context.Context, token string, remoteIP string, resource string) error {
+	if len(token) == 0 {
+		return util.NewErrorf(util.ErrCodeForbidden, "captcha response not provided")
+	}
+
+	form := url.Values{
+		"secret":   {v.secret},
+		"response": {token},
+	}
+	if len(remoteIP) > 0 {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error creating request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error during http request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return util.NewErrorf(util.ErrCodeUnknown, "unexpected status code %d from captcha provider", resp.StatusCode)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
+	}
+
+	if !result.Success {
+		return util.NewErrorf(util.ErrCodeForbidden, "captcha verification failed: %s", strings.Join(result.ErrorCodes, ", "))
+	}
+
+	return nil
+}
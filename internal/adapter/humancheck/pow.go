@@ -0,0 +1,89 @@
+package humancheck
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const (
+	// proofWindow is how long a proof of work stays valid after the
+	// timestamp it was computed for.
+	proofWindow   = 10 * time.Minute
+	maxClockSkew  = time.Minute
+	maxDifficulty = 32
+)
+
+// ProofOfWork accepts hashcash style proofs. The token is "<timestamp>:<nonce>"
+// where timestamp is in unix seconds and the SHA-256 of
+// "<resource>\n<remote ip>\n<timestamp>\n<nonce>" starts with difficulty zero
+// bits. Each proof is accepted once, as long as replays remembers it.
+type ProofOfWork struct {
+	difficulty int
+	replays    port.ReplayGuard
+}
+
+func NewProofOfWork(difficulty int, replays port.ReplayGuard) *ProofOfWork {
+	if difficulty > maxDifficulty {
+		difficulty = maxDifficulty
+	}
+
+	return &ProofOfWork{
+		difficulty: difficulty,
+		replays:    replays,
+	}
+}
+
+func (p *ProofOfWork) Verify(ctx context.Context, token string, remoteIP string, resource string) error {
+	fields := strings.SplitN(token, ":", 2)
+	if len(fields) != 2 || len(fields[1]) == 0 {
+		return util.NewErrorf(util.ErrCodeForbidden, "proof of work not provided")
+	}
+
+	seconds, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeForbidden, "invalid proof of work timestamp")
+	}
+
+	now := time.Now()
+	issued := time.Unix(seconds, 0)
+	if issued.After(now.Add(maxClockSkew)) || now.Sub(issued) > proofWindow {
+		return util.NewErrorf(util.ErrCodeForbidden, "proof of work timestamp out of range")
+	}
+
+	sum := sha256.Sum256([]byte(resource + "\n" + remoteIP + "\n" + fields[0] + "\n" + fields[1]))
+	if leadingZeros(sum[:]) < p.difficulty {
+		return util.NewErrorf(util.ErrCodeForbidden, "insufficient proof of work")
+	}
+
+	// the proof is remembered until its timestamp is out of range anyway
+	unused, err := p.replays.Claim(ctx, "pow:"+hex.EncodeToString(sum[:]), issued.Add(proofWindow).Sub(now)+maxClockSkew)
+	if err != nil {
+		return err
+	}
+
+	if !unused {
+		return util.NewErrorf(util.ErrCodeForbidden, "proof of work already used")
+	}
+
+	return nil
+}
+
+func leadingZeros(sum []byte) int {
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+
+	return zeros
+}
@@ -0,0 +1,29 @@
+package keycloak
+
+import (
+	"github.com/hugosrc/shortlink/internal/adapter/oidc"
+	"github.com/spf13/viper"
+)
+
+// NewOpenIDAuth creates an OpenID Connect authenticator for a Keycloak
+// realm from the KEYCLOAK_* configuration keys. Roles are read from the
+// realm roles and the roles of the client the token was issued to.
+func NewOpenIDAuth(config *viper.Viper) (*oidc.Auth, error) {
+	authorizedParty := config.GetString("KEYCLOAK_OIDC_AUTHORIZED_PARTY")
+
+	return oidc.New(oidc.Config{
+		Issuer:          config.GetString("KEYCLOAK_OIDC_ISSUER"),
+		JWKSURL:         config.GetString("KEYCLOAK_OIDC_CERTS"),
+		Audiences:       oidc.SplitList(config.GetString("KEYCLOAK_OIDC_AUDIENCE")),
+		AuthorizedParty: authorizedParty,
+		Algorithms:      oidc.SplitList(config.GetString("KEYCLOAK_OIDC_ALGORITHMS")),
+		ClockSkew:       config.GetDuration("KEYCLOAK_OIDC_CLOCK_SKEW"),
+		Claims: oidc.ClaimMapping{
+			UserID:   "sub",
+			Username: "preferred_username",
+			Roles:    []string{"realm_access.roles", "resource_access." + authorizedParty + ".roles"},
+			Scopes:   "scope",
+			Groups:   "groups",
+		},
+	})
+}
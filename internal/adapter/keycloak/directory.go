@@ -0,0 +1,196 @@
+package keycloak
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/spf13/viper"
+)
+
+const (
+	displayNameTTL = 10 * time.Minute
+	// failedNameTTL keeps users that couldn't be looked up from being
+	// looked up again on every request.
+	failedNameTTL = time.Minute
+	// maxCachedNames bounds the cache, which is pruned of expired names
+	// when full.
+	maxCachedNames = 10000
+	// maxLookups bounds the concurrent calls to the admin API. Lookups
+	// beyond it fail rather than wait, as names are informative only.
+	maxLookups = 4
+)
+
+type cachedName struct {
+	name    string
+	expires time.Time
+}
+
+// UserDirectory looks up users through the Keycloak admin API, authenticated
+// with the service account of the configured client. Names are cached, as
+// are failed lookups, so that public requests don't reach the admin API
+// more than once per user and TTL.
+type UserDirectory struct {
+	config  *viper.Viper
+	client  *http.Client
+	lookups chan struct{}
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+	names       map[string]cachedName
+}
+
+func NewUserDirectory(config *viper.Viper) *UserDirectory {
+	return &UserDirectory{
+		config:  config,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		lookups: make(chan struct{}, maxLookups),
+		names:   make(map[string]cachedName),
+	}
+}
+
+func (d *UserDirectory) DisplayName(ctx context.Context, userID string) (string, error) {
+	d.mu.Lock()
+	cached, ok := d.names[userID]
+	d.mu.Unlock()
+
+	if ok && time.Now().Before(cached.expires) {
+		if len(cached.name) == 0 {
+			return "", util.NewErrorf(util.ErrCodeNotFound, "user not found")
+		}
+
+		return cached.name, nil
+	}
+
+	select {
+	case d.lookups <- struct{}{}:
+		defer func() { <-d.lookups }()
+	default:
+		return "", util.NewErrorf(util.ErrCodeUnknown, "too many user lookups in progress")
+	}
+
+	name, err := d.lookup(ctx, userID)
+	if err != nil {
+		d.cache(userID, "", failedNameTTL)
+		return "", err
+	}
+
+	d.cache(userID, name, displayNameTTL)
+
+	return name, nil
+}
+
+func (d *UserDirectory) cache(userID string, name string, ttl time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if len(d.names) >= maxCachedNames {
+		for id, cached := range d.names {
+			if now.After(cached.expires) {
+				delete(d.names, id)
+			}
+		}
+	}
+
+	if len(d.names) < maxCachedNames {
+		d.names[userID] = cachedName{name: name, expires: now.Add(ttl)}
+	}
+}
+
+func (d *UserDirectory) lookup(ctx context.Context, userID string) (string, error) {
+	token, err := d.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	usersURL := strings.TrimSuffix(d.config.GetString("KEYCLOAK_ADMIN_URL"), "/") + "/users/" + url.PathEscape(userID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, usersURL, nil)
+	if err != nil {
+		return "", util.WrapErrorf(err, util.ErrCodeUnknown, "error creating request")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return "", util.WrapErrorf(err, util.ErrCodeUnknown, "error during http request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", util.NewErrorf(util.ErrCodeNotFound, "user not found")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", util.NewErrorf(util.ErrCodeUnknown, "failed to get user")
+	}
+
+	var user struct {
+		Username  string `json:"username"`
+		FirstName string `json:"firstName"`
+		LastName  string `json:"lastName"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return "", util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
+	}
+
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if len(name) == 0 {
+		name = user.Username
+	}
+
+	return name, nil
+}
+
+// accessToken returns a service account token obtained with the client
+// credentials grant, reusing it until shortly before it expires.
+func (d *UserDirectory) accessToken(ctx context.Context) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.token) > 0 && time.Now().Before(d.tokenExpiry) {
+		return d.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {d.config.GetString("KEYCLOAK_CLIENT_ID")},
+		"client_secret": {d.config.GetString("KEYCLOAK_CLIENT_SECRET")},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.config.GetString("KEYCLOAK_OIDC_TOKEN_URL"),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", util.WrapErrorf(err, util.ErrCodeUnknown, "error creating request")
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return "", util.WrapErrorf(err, util.ErrCodeUnknown, "error during http request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", util.NewErrorf(util.ErrCodeUnknown, "failed to get service account token")
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
+	}
+
+	d.token = token.AccessToken
+	d.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - 30*time.Second)
+
+	return d.token, nil
+}
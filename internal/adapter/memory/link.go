@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// LinkCaching keeps the most recently used links of a single process.
+// Links are stored encoded, so callers can't modify the cached copies.
+type LinkCaching struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type cacheEntry struct {
+	hash string
+	data []byte
+}
+
+// NewLinkCaching creates a cache evicting the least recently used link
+// beyond capacity links.
+func NewLinkCaching(capacity int) *LinkCaching {
+	return &LinkCaching{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *LinkCaching) Get(ctx context.Context, hash string) (*domain.Link, error) {
+	c.mu.Lock()
+	element, ok := c.entries[hash]
+	if ok {
+		c.order.MoveToFront(element)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		return nil, nil
+	}
+
+	var link domain.Link
+	if err := json.Unmarshal(element.Value.(*cacheEntry).data, &link); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
+	}
+
+	return &link, nil
+}
+
+func (c *LinkCaching) Set(ctx context.Context, link *domain.Link) error {
+	data, err := json.Marshal(link)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "json marshal error")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[link.Hash]; ok {
+		element.Value = &cacheEntry{hash: link.Hash, data: data}
+		c.order.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[link.Hash] = c.order.PushFront(&cacheEntry{hash: link.Hash, data: data})
+
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).hash)
+	}
+
+	return nil
+}
+
+func (c *LinkCaching) Del(ctx context.Context, hash string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[hash]; ok {
+		c.order.Remove(element)
+		delete(c.entries, hash)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// sweepInterval is how often keys whose limits have fully reset are
+// dropped, so the limiter doesn't grow with every client ever seen.
+const sweepInterval = time.Minute
+
+// RateLimiter is a process local implementation of the generic cell rate
+// algorithm (GCRA). It only keeps the theoretical arrival time of each key.
+type RateLimiter struct {
+	mu        sync.Mutex
+	tat       map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		tat: make(map[string]time.Time),
+		now: time.Now,
+	}
+}
+
+func (l *RateLimiter) Allow(ctx context.Context, key string, limit domain.RateLimit) (*domain.RateLimitDecision, error) {
+	if limit.Limit <= 0 || limit.Period <= 0 {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "invalid rate limit %d/%s", limit.Limit, limit.Period)
+	}
+
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Limit
+	}
+
+	interval := limit.Period / time.Duration(limit.Limit)
+	tolerance := interval * time.Duration(burst)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	tat := l.tat[key]
+	if tat.Before(now) {
+		tat = now
+	}
+
+	newTat := tat.Add(interval)
+	allowAt := newTat.Add(-tolerance)
+
+	if now.Before(allowAt) {
+		return &domain.RateLimitDecision{
+			Allowed:    false,
+			Limit:      burst,
+			Remaining:  0,
+			ResetAfter: tat.Sub(now),
+			RetryAfter: allowAt.Sub(now),
+		}, nil
+	}
+
+	l.tat[key] = newTat
+
+	return &domain.RateLimitDecision{
+		Allowed:    true,
+		Limit:      burst,
+		Remaining:  int(now.Sub(allowAt) / interval),
+		ResetAfter: newTat.Sub(now),
+	}, nil
+}
+
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+
+	for key, tat := range l.tat {
+		if tat.Before(now) {
+			delete(l.tat, key)
+		}
+	}
+
+	l.lastSweep = now
+}
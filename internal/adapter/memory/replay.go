@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ReplayGuard remembers the tokens claimed by this process until they
+// expire.
+type ReplayGuard struct {
+	mu        sync.Mutex
+	used      map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewReplayGuard() *ReplayGuard {
+	return &ReplayGuard{
+		used: make(map[string]time.Time),
+	}
+}
+
+func (g *ReplayGuard) Claim(ctx context.Context, token string, ttl time.Duration) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.sweep(now)
+
+	if expiry, ok := g.used[token]; ok && now.Before(expiry) {
+		return false, nil
+	}
+
+	g.used[token] = now.Add(ttl)
+
+	return true, nil
+}
+
+func (g *ReplayGuard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < sweepInterval {
+		return
+	}
+
+	for token, expiry := range g.used {
+		if !now.Before(expiry) {
+			delete(g.used, token)
+		}
+	}
+
+	g.lastSweep = now
+}
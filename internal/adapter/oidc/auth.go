@@ -0,0 +1,322 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// keySource resolves the key a token was signed with.
+type keySource interface {
+	Key(ctx context.Context, kid string) (*jose.JSONWebKey, error)
+}
+
+// Auth authenticates requests with bearer tokens issued by any OpenID
+// Connect provider, or signed with static keys for tests and development.
+type Auth struct {
+	conf       Config
+	keys       keySource
+	stop       func()
+	algorithms map[string]bool
+}
+
+// New creates the authenticator. Keys are fetched from the issuer, through
+// discovery unless JWKSURL is set, and refreshed in the background until
+// Close is called, and tokens must be issued to one of the configured
+// audiences, since the issuer signs tokens for its other clients with the
+// same keys. When HS256Secret or JWKSFile is set the keys are static, the
+// issuer is never contacted and the audience is optional.
+func New(conf Config) (*Auth, error) {
+	conf = conf.withDefaults()
+
+	auth := &Auth{
+		conf:       conf,
+		stop:       func() {},
+		algorithms: make(map[string]bool, len(conf.Algorithms)),
+	}
+
+	for _, alg := range conf.Algorithms {
+		auth.algorithms[alg] = true
+	}
+
+	switch {
+	case len(conf.HS256Secret) > 0 && len(conf.JWKSFile) > 0:
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "hs256 secret and jwks file are mutually exclusive")
+	case len(conf.HS256Secret) > 0:
+		keys, err := NewSecretKeySet(conf.HS256Secret)
+		if err != nil {
+			return nil, err
+		}
+		auth.keys = keys
+	case len(conf.JWKSFile) > 0:
+		keys, err := LoadKeySet(conf.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		auth.keys = keys
+	case len(conf.Issuer) > 0:
+		if len(conf.Audiences) == 0 {
+			return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "audiences are required with the issuer's keys")
+		}
+
+		keys := NewKeySet(conf.Issuer, conf.JWKSURL)
+		keys.Start()
+		auth.keys = keys
+		auth.stop = keys.Stop
+	default:
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "issuer url is required")
+	}
+
+	return auth, nil
+}
+
+func (a *Auth) Close() {
+	a.stop()
+}
+
+// Authenticate verifies the bearer token of the request. Claims are only
+// read once the signature has been checked against the issuer's keys.
+func (a *Auth) Authenticate(r *http.Request, w http.ResponseWriter) (*domain.Principal, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := a.verifySignature(r.Context(), token)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnauthorized, "token signature")
+	}
+
+	if err := a.verifyTime(claims, time.Now()); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnauthorized, "token validity")
+	}
+
+	if err := a.verifyIssuer(claims); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnauthorized, "token issuer")
+	}
+
+	if err := a.verifyAudience(claims); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnauthorized, "token audience")
+	}
+
+	if err := a.verifyAuthorizedParty(claims); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnauthorized, "token azp")
+	}
+
+	mapping := a.conf.Claims
+
+	userID, ok := lookupClaim(claims, mapping.UserID).(string)
+	if !ok || len(userID) == 0 {
+		return nil, util.NewErrorf(util.ErrCodeUnauthorized, "token issued without %s", mapping.UserID)
+	}
+
+	username, _ := lookupClaim(claims, mapping.Username).(string)
+
+	var roles []string
+	for _, path := range mapping.Roles {
+		roles = append(roles, stringsClaim(lookupClaim(claims, path))...)
+	}
+
+	return &domain.Principal{
+		UserID:   userID,
+		Username: username,
+		Roles:    roles,
+		Scopes:   stringsClaim(lookupClaim(claims, mapping.Scopes)),
+		Groups:   stringsClaim(lookupClaim(claims, mapping.Groups)),
+	}, nil
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if len(authHeader) == 0 {
+		return "", util.NewErrorf(util.ErrCodeUnauthorized, "jwt token not provided")
+	}
+
+	fields := strings.Fields(authHeader)
+	if len(fields) != 2 || !strings.EqualFold(fields[0], "Bearer") {
+		return "", util.NewErrorf(util.ErrCodeUnauthorized, "authorization header does not follow the format 'Authorization: Bearer <string with JWT>'")
+	}
+
+	return fields[1], nil
+}
+
+// verifySignature checks the token was signed by the issuer with one of the
+// allowed algorithms and returns the claims of the verified payload.
+func (a *Auth) verifySignature(ctx context.Context, token string) (map[string]interface{}, error) {
+	jws, err := jose.ParseSigned(token)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "malformed jwt token")
+	}
+
+	if len(jws.Signatures) != 1 {
+		return nil, util.NewErrorf(util.ErrCodeUnknown, "token must have exactly one signature")
+	}
+
+	header := jws.Signatures[0].Header
+	if !a.algorithms[header.Algorithm] {
+		return nil, util.NewErrorf(util.ErrCodeUnknown, "signing algorithm %q is not allowed", header.Algorithm)
+	}
+
+	key, err := a.keys.Key(ctx, header.KeyID)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "token signature keys")
+	}
+
+	if len(key.Algorithm) > 0 && key.Algorithm != header.Algorithm {
+		return nil, util.NewErrorf(util.ErrCodeUnknown, "key %q is not used with %q", key.KeyID, header.Algorithm)
+	}
+
+	payload, err := jws.Verify(key)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "invalid token signature")
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
+	}
+
+	return claims, nil
+}
+
+// verifyTime checks the exp, nbf and iat claims, tolerating the configured
+// clock skew between this service and the issuer.
+func (a *Auth) verifyTime(claims map[string]interface{}, now time.Time) error {
+	skew := a.conf.ClockSkew
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return util.NewErrorf(util.ErrCodeUnknown, "token issued without exp")
+	}
+
+	if now.After(time.Unix(int64(exp), 0).Add(skew)) {
+		return util.NewErrorf(util.ErrCodeUnknown, "token is expired")
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(skew).Before(time.Unix(int64(nbf), 0)) {
+		return util.NewErrorf(util.ErrCodeUnknown, "token is not valid yet")
+	}
+
+	if iat, ok := claims["iat"].(float64); ok && now.Add(skew).Before(time.Unix(int64(iat), 0)) {
+		return util.NewErrorf(util.ErrCodeUnknown, "token issued in the future")
+	}
+
+	return nil
+}
+
+// verifyAudience accepts the token when it was issued to any of the
+// configured audiences.
+func (a *Auth) verifyAudience(claims map[string]interface{}) error {
+	if len(a.conf.Audiences) == 0 {
+		return nil
+	}
+
+	tokenAud, ok := claims["aud"]
+	if !ok {
+		return util.NewErrorf(util.ErrCodeUnknown, "token issued without audience")
+	}
+
+	var audiences []string
+	switch aud := tokenAud.(type) {
+	case string:
+		audiences = []string{aud}
+	case []interface{}:
+		audiences = stringsClaim(aud)
+	default:
+		return util.NewErrorf(util.ErrCodeUnknown, "invalid audience type")
+	}
+
+	for _, aud := range audiences {
+		for _, expected := range a.conf.Audiences {
+			if aud == expected {
+				return nil
+			}
+		}
+	}
+
+	return util.NewErrorf(util.ErrCodeUnknown, "token issued to another audience")
+}
+
+func (a *Auth) verifyAuthorizedParty(claims map[string]interface{}) error {
+	tokenAzp, _ := claims["azp"].(string)
+	if len(tokenAzp) == 0 || len(a.conf.AuthorizedParty) == 0 {
+		return nil
+	}
+
+	if tokenAzp != a.conf.AuthorizedParty {
+		return util.NewErrorf(util.ErrCodeUnknown, "token issued to another authorized party")
+	}
+
+	return nil
+}
+
+// verifyIssuer compares the iss claim with the configured issuer. Static
+// keys may be used without an issuer, in which case any is accepted.
+func (a *Auth) verifyIssuer(claims map[string]interface{}) error {
+	if len(a.conf.Issuer) == 0 {
+		return nil
+	}
+
+	tokenIssuer, ok := claims["iss"].(string)
+	if !ok {
+		return util.NewErrorf(util.ErrCodeUnknown, "token issuer is invalid")
+	}
+
+	if strings.TrimSuffix(tokenIssuer, "/") != strings.TrimSuffix(a.conf.Issuer, "/") {
+		return util.NewErrorf(util.ErrCodeUnknown, "unrecognized token issuer")
+	}
+
+	return nil
+}
+
+// lookupClaim resolves a dotted claim path such as realm_access.roles.
+// Claim names containing dots, like the namespaced claims of Auth0, are
+// matched as a whole before being split.
+func lookupClaim(claims map[string]interface{}, path string) interface{} {
+	if len(path) == 0 {
+		return nil
+	}
+
+	if value, ok := claims[path]; ok {
+		return value
+	}
+
+	for i := 0; i < len(path); i++ {
+		if path[i] != '.' {
+			continue
+		}
+
+		if nested, ok := claims[path[:i]].(map[string]interface{}); ok {
+			if value := lookupClaim(nested, path[i+1:]); value != nil {
+				return value
+			}
+		}
+	}
+
+	return nil
+}
+
+// stringsClaim reads a claim holding either a list of strings or a single
+// space separated string, the format of the scope claim.
+func stringsClaim(claim interface{}) []string {
+	if value, ok := claim.(string); ok {
+		return strings.Fields(value)
+	}
+
+	values, _ := claim.([]interface{})
+
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if v, ok := value.(string); ok {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
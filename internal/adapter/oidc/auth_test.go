@@ -0,0 +1,404 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/util"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com/realms/shortlink"
+	testAudience = "shortlink"
+	testParty    = "link-service"
+	testSecret   = "0123456789abcdef0123456789abcdef"
+)
+
+func TestAuthenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingKey := jose.JSONWebKey{Key: rsaKey, KeyID: "rsa", Algorithm: string(jose.RS256), Use: "sig"}
+
+	rsaAuth := newTestAuth(t, Config{JWKSFile: writeKeySet(t, signingKey)})
+	// accepts RS512 too, but the key is only used with RS256
+	multiAlgAuth := newTestAuth(t, Config{
+		JWKSFile:   writeKeySet(t, signingKey),
+		Algorithms: []string{string(jose.RS256), string(jose.RS512)},
+	})
+	secretAuth := newTestAuth(t, Config{HS256Secret: testSecret})
+
+	now := time.Now()
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss": testIssuer,
+			"aud": []string{testAudience, "account"},
+			"azp": testParty,
+			"sub": "5a0c3c8e-7c8b-4a7e-9c1e-2f1e6f0f8a11",
+			"exp": now.Add(5 * time.Minute).Unix(),
+			"iat": now.Unix(),
+			"nbf": now.Unix(),
+		}
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := valid()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+
+		return claims
+	}
+
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustMarshalPKIX(t, &rsaKey.PublicKey)})
+
+	token := sign(t, jose.RS256, &signingKey, valid())
+	parts := strings.Split(token, ".")
+
+	tests := []struct {
+		name    string
+		auth    *Auth
+		header  string
+		wantErr bool
+	}{
+		{
+			name:   "valid rs256 token",
+			auth:   rsaAuth,
+			header: "Bearer " + token,
+		},
+		{
+			name:   "valid hs256 token",
+			auth:   secretAuth,
+			header: "Bearer " + sign(t, jose.HS256, []byte(testSecret), valid()),
+		},
+		{
+			name:    "signature by another key with the same kid",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS256, &jose.JSONWebKey{Key: otherKey, KeyID: "rsa"}, valid()),
+			wantErr: true,
+		},
+		{
+			name:    "tampered payload",
+			auth:    rsaAuth,
+			header:  "Bearer " + parts[0] + "." + encodeSegment(t, with("sub", "admin")) + "." + parts[2],
+			wantErr: true,
+		},
+		{
+			name:    "signed by an unknown key",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS256, &jose.JSONWebKey{Key: otherKey, KeyID: "other"}, valid()),
+			wantErr: true,
+		},
+		{
+			name:    "algorithm not allowed",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS512, &jose.JSONWebKey{Key: rsaKey, KeyID: "rsa"}, valid()),
+			wantErr: true,
+		},
+		{
+			name:    "alg none",
+			auth:    rsaAuth,
+			header:  "Bearer " + encodeSegment(t, map[string]string{"alg": "none", "kid": "rsa"}) + "." + encodeSegment(t, valid()) + ".",
+			wantErr: true,
+		},
+		{
+			name:    "hs256 token signed with the rs256 public key",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.HS256, []byte(publicPEM), valid(), "rsa"),
+			wantErr: true,
+		},
+		{
+			name:    "hs256 token against an rs256 key with both algorithms allowed",
+			auth:    newTestAuth(t, Config{JWKSFile: writeKeySet(t, signingKey), Algorithms: []string{string(jose.RS256), string(jose.HS256)}}),
+			header:  "Bearer " + sign(t, jose.HS256, []byte(publicPEM), valid(), "rsa"),
+			wantErr: true,
+		},
+		{
+			name:    "key algorithm mismatch",
+			auth:    multiAlgAuth,
+			header:  "Bearer " + sign(t, jose.RS512, &jose.JSONWebKey{Key: rsaKey, KeyID: "rsa"}, valid()),
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS256, &signingKey, with("exp", now.Add(-time.Minute).Unix())),
+			wantErr: true,
+		},
+		{
+			name:   "expired within the clock skew",
+			auth:   rsaAuth,
+			header: "Bearer " + sign(t, jose.RS256, &signingKey, with("exp", now.Add(-10*time.Second).Unix())),
+		},
+		{
+			name:    "missing exp",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS256, &signingKey, with("exp", nil)),
+			wantErr: true,
+		},
+		{
+			name:    "not valid yet",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS256, &signingKey, with("nbf", now.Add(5*time.Minute).Unix())),
+			wantErr: true,
+		},
+		{
+			name:    "issued in the future",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS256, &signingKey, with("iat", now.Add(5*time.Minute).Unix())),
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS256, &signingKey, with("iss", "https://evil.example.com")),
+			wantErr: true,
+		},
+		{
+			name:    "missing issuer",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS256, &signingKey, with("iss", nil)),
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS256, &signingKey, with("aud", "another-client")),
+			wantErr: true,
+		},
+		{
+			name:    "missing audience",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS256, &signingKey, with("aud", nil)),
+			wantErr: true,
+		},
+		{
+			name:    "wrong authorized party",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS256, &signingKey, with("azp", "another-client")),
+			wantErr: true,
+		},
+		{
+			name:    "missing subject",
+			auth:    rsaAuth,
+			header:  "Bearer " + sign(t, jose.RS256, &signingKey, with("sub", nil)),
+			wantErr: true,
+		},
+		{
+			name:    "no authorization header",
+			auth:    rsaAuth,
+			wantErr: true,
+		},
+		{
+			name:    "basic scheme",
+			auth:    rsaAuth,
+			header:  "Basic dXNlcjpwYXNz",
+			wantErr: true,
+		},
+		{
+			name:    "bearer without token",
+			auth:    rsaAuth,
+			header:  "Bearer",
+			wantErr: true,
+		},
+		{
+			name:    "bearer with extra fields",
+			auth:    rsaAuth,
+			header:  "Bearer " + token + " extra",
+			wantErr: true,
+		},
+		{
+			name:    "not a jwt",
+			auth:    rsaAuth,
+			header:  "Bearer not-a-jwt",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/shortlink", nil)
+			if len(tt.header) > 0 {
+				r.Header.Set("Authorization", tt.header)
+			}
+
+			principal, err := tt.auth.Authenticate(r, httptest.NewRecorder())
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Authenticate() error = %v", err)
+				}
+
+				if principal.UserID != valid()["sub"] {
+					t.Errorf("UserID = %q, want %q", principal.UserID, valid()["sub"])
+				}
+
+				return
+			}
+
+			var appError *util.Error
+			if !errors.As(err, &appError) || appError.Code() != util.ErrCodeUnauthorized {
+				t.Fatalf("Authenticate() error = %v, want an unauthorized error", err)
+			}
+		})
+	}
+}
+
+func newTestAuth(t *testing.T, conf Config) *Auth {
+	t.Helper()
+
+	conf.Issuer = testIssuer
+	conf.Audiences = []string{testAudience}
+	conf.AuthorizedParty = testParty
+	conf.ClockSkew = 30 * time.Second
+
+	auth, err := New(conf)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(auth.Close)
+
+	return auth
+}
+
+// writeKeySet stores the keys in a JWKS file, as OIDC_JWKS_FILE.
+func writeKeySet(t *testing.T, keys ...jose.JSONWebKey) string {
+	t.Helper()
+
+	data, err := json.Marshal(jose.JSONWebKeySet{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// sign creates a compact JWS of the claims. The kid header is set from the
+// key when it is a JSON Web Key, or from kid otherwise.
+func sign(t *testing.T, alg jose.SignatureAlgorithm, key interface{}, claims interface{}, kid ...string) string {
+	t.Helper()
+
+	opts := (&jose.SignerOptions{}).WithType("JWT")
+	if len(kid) > 0 {
+		opts = opts.WithHeader("kid", kid[0])
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func mustMarshalPKIX(t *testing.T, key *rsa.PublicKey) []byte {
+	t.Helper()
+
+	data, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestNewRequiresAudiencesWithIssuerKeys(t *testing.T) {
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"keys":[]}`))
+	}))
+	defer jwks.Close()
+
+	tests := []struct {
+		name    string
+		conf    Config
+		wantErr bool
+	}{
+		{
+			name:    "issuer without audiences",
+			conf:    Config{Issuer: testIssuer, JWKSURL: jwks.URL},
+			wantErr: true,
+		},
+		{
+			name: "issuer with audiences",
+			conf: Config{Issuer: testIssuer, JWKSURL: jwks.URL, Audiences: []string{testAudience}},
+		},
+		{
+			name: "hs256 secret without audiences",
+			conf: Config{HS256Secret: testSecret},
+		},
+		{
+			name: "jwks file without audiences",
+			conf: Config{JWKSFile: writeKeySet(t, jose.JSONWebKey{Key: []byte(testSecret), KeyID: "oct", Algorithm: string(jose.HS256)})},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := New(tt.conf)
+			if tt.wantErr {
+				if err == nil {
+					auth.Close()
+					t.Fatal("New() succeeded, want an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			auth.Close()
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package oidc
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// Config describes the provider the tokens are issued by.
+type Config struct {
+	// Issuer is the provider's issuer URL, used for discovery and to check
+	// the iss claim. It may be empty with static keys.
+	Issuer string
+	// JWKSURL overrides the jwks_uri of the discovery document.
+	JWKSURL string
+	// JWKSFile and HS256Secret replace the provider's keys with static ones.
+	JWKSFile    string
+	HS256Secret string
+
+	// Audiences accepted in the aud claim. They are required with the
+	// issuer's keys; with static keys no check is made when empty.
+	Audiences []string
+	// AuthorizedParty is compared with the azp claim when both are set.
+	AuthorizedParty string
+	Algorithms      []string
+	ClockSkew       time.Duration
+
+	Claims ClaimMapping
+}
+
+// ClaimMapping names the claims the principal is read from. Each name is a
+// dotted path into the token's claims, such as realm_access.roles.
+type ClaimMapping struct {
+	UserID   string
+	Username string
+	Roles    []string
+	Scopes   string
+	Groups   string
+}
+
+// NewConfig reads the OIDC_* configuration keys.
+func NewConfig(config *viper.Viper) Config {
+	return Config{
+		Issuer:          config.GetString("OIDC_ISSUER_URL"),
+		JWKSURL:         config.GetString("OIDC_JWKS_URL"),
+		JWKSFile:        config.GetString("OIDC_JWKS_FILE"),
+		HS256Secret:     config.GetString("OIDC_HS256_SECRET"),
+		Audiences:       SplitList(config.GetString("OIDC_AUDIENCES")),
+		AuthorizedParty: config.GetString("OIDC_AUTHORIZED_PARTY"),
+		Algorithms:      SplitList(config.GetString("OIDC_ALGORITHMS")),
+		ClockSkew:       config.GetDuration("OIDC_CLOCK_SKEW"),
+		Claims: ClaimMapping{
+			UserID:   config.GetString("OIDC_USER_ID_CLAIM"),
+			Username: config.GetString("OIDC_USERNAME_CLAIM"),
+			Roles:    SplitList(config.GetString("OIDC_ROLES_CLAIMS")),
+			Scopes:   config.GetString("OIDC_SCOPES_CLAIM"),
+			Groups:   config.GetString("OIDC_GROUPS_CLAIM"),
+		},
+	}
+}
+
+// withDefaults fills in the standard claims and, unless configured, only
+// accepts the algorithm matching the kind of keys in use.
+func (c Config) withDefaults() Config {
+	if len(c.Algorithms) == 0 {
+		if len(c.HS256Secret) > 0 {
+			c.Algorithms = []string{string(jose.HS256)}
+		} else {
+			c.Algorithms = []string{string(jose.RS256)}
+		}
+	}
+
+	if len(c.Claims.UserID) == 0 {
+		c.Claims.UserID = "sub"
+	}
+
+	if len(c.Claims.Username) == 0 {
+		c.Claims.Username = "preferred_username"
+	}
+
+	if len(c.Claims.Scopes) == 0 {
+		c.Claims.Scopes = "scope"
+	}
+
+	if len(c.Claims.Groups) == 0 {
+		c.Claims.Groups = "groups"
+	}
+
+	return c
+}
+
+// SplitList splits a comma or space separated configuration value.
+func SplitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
@@ -0,0 +1,11 @@
+package oidc
+
+import "context"
+
+// NopDirectory is used with providers whose user store can't be queried.
+// Owners are shown without a display name.
+type NopDirectory struct{}
+
+func (NopDirectory) DisplayName(ctx context.Context, userID string) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,249 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/util"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	defaultKeysTTL     = time.Hour
+	minKeysTTL         = time.Minute
+	minRefreshInterval = 10 * time.Second
+)
+
+// KeySet is a concurrent-safe cache of the provider's JSON Web Keys. Keys are
+// refreshed in the background when the response's Cache-Control max-age
+// expires, and on demand when a token is signed with an unknown key id, at
+// most once every minRefreshInterval.
+type KeySet struct {
+	client    *http.Client
+	issuer    string
+	jwksURL   string
+	refreshMu sync.Mutex
+
+	mu     sync.RWMutex
+	keys   []jose.JSONWebKey
+	expiry time.Time
+	// lastAttempt is when the keys were last fetched, successfully or
+	// not, to rate limit on-demand refreshes while the provider fails.
+	lastAttempt time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewKeySet creates a key set fetching keys from jwksURL or, when empty,
+// from the jwks_uri announced by the issuer's discovery document.
+func NewKeySet(issuer string, jwksURL string) *KeySet {
+	return &KeySet{
+		client:  &http.Client{Timeout: 10 * time.Second},
+		issuer:  strings.TrimSuffix(issuer, "/"),
+		jwksURL: jwksURL,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start refreshes the keys in the background until Stop is called.
+func (ks *KeySet) Start() {
+	go func() {
+		defer close(ks.done)
+
+		for {
+			wait := minRefreshInterval
+			if err := ks.refresh(context.Background(), false); err == nil {
+				ks.mu.RLock()
+				wait = time.Until(ks.expiry)
+				ks.mu.RUnlock()
+			}
+
+			select {
+			case <-ks.stop:
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+func (ks *KeySet) Stop() {
+	close(ks.stop)
+	<-ks.done
+}
+
+// Key returns the key identified by kid, fetching the key set again if the
+// id is unknown. Tokens without a kid can only be verified when the set has
+// a single signing key.
+func (ks *KeySet) Key(ctx context.Context, kid string) (*jose.JSONWebKey, error) {
+	if key := ks.lookup(kid); key != nil {
+		return key, nil
+	}
+
+	if err := ks.refresh(ctx, true); err != nil {
+		return nil, err
+	}
+
+	if key := ks.lookup(kid); key != nil {
+		return key, nil
+	}
+
+	return nil, util.NewErrorf(util.ErrCodeUnknown, "unknown signing key %q", kid)
+}
+
+func (ks *KeySet) lookup(kid string) *jose.JSONWebKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return lookupKey(ks.keys, kid)
+}
+
+func lookupKey(keys []jose.JSONWebKey, kid string) *jose.JSONWebKey {
+	if len(kid) == 0 {
+		var found *jose.JSONWebKey
+		for i := range keys {
+			if keys[i].Use == "" || keys[i].Use == "sig" {
+				if found != nil {
+					return nil
+				}
+				found = &keys[i]
+			}
+		}
+
+		return found
+	}
+
+	for i := range keys {
+		if keys[i].KeyID == kid {
+			return &keys[i]
+		}
+	}
+
+	return nil
+}
+
+// refresh fetches the key set. On-demand refreshes are skipped when the
+// keys were fetched, or failed to be, less than minRefreshInterval ago, so
+// tokens with bogus key ids can't be used to flood the provider, even
+// while it is down.
+func (ks *KeySet) refresh(ctx context.Context, onDemand bool) error {
+	ks.refreshMu.Lock()
+	defer ks.refreshMu.Unlock()
+
+	ks.mu.Lock()
+	lastAttempt := ks.lastAttempt
+	if onDemand && time.Since(lastAttempt) < minRefreshInterval {
+		ks.mu.Unlock()
+		return nil
+	}
+	ks.lastAttempt = time.Now()
+	ks.mu.Unlock()
+
+	jwksURL, err := ks.keysURL(ctx)
+	if err != nil {
+		return err
+	}
+
+	var keySet jose.JSONWebKeySet
+	resp, err := ks.getJSON(ctx, jwksURL, &keySet)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	ks.keys = keySet.Keys
+	ks.expiry = time.Now().Add(keysTTL(resp))
+	ks.mu.Unlock()
+
+	return nil
+}
+
+// keysURL resolves the JWKS endpoint, using OpenID Connect discovery
+// unless it is explicitly configured.
+func (ks *KeySet) keysURL(ctx context.Context) (string, error) {
+	if len(ks.jwksURL) > 0 {
+		return ks.jwksURL, nil
+	}
+
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+
+	if _, err := ks.getJSON(ctx, ks.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return "", err
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != ks.issuer {
+		return "", util.NewErrorf(util.ErrCodeUnknown, "discovery document issued by %q", discovery.Issuer)
+	}
+
+	if len(discovery.JWKSURI) == 0 {
+		return "", util.NewErrorf(util.ErrCodeUnknown, "discovery document without jwks_uri")
+	}
+
+	ks.jwksURL = discovery.JWKSURI
+
+	return ks.jwksURL, nil
+}
+
+func (ks *KeySet) getJSON(ctx context.Context, url string, v interface{}) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error creating request")
+	}
+
+	req.Header.Set("Accept", "application/json")
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error during http request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, util.NewErrorf(util.ErrCodeUnknown, "unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
+	}
+
+	return resp, nil
+}
+
+// keysTTL reads how long the keys may be cached from the Cache-Control
+// max-age directive, falling back to the Expires header and then to an
+// hour.
+func keysTTL(resp *http.Response) time.Duration {
+	ttl := defaultKeysTTL
+
+	for _, directive := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(directive)
+		if strings.HasPrefix(directive, "max-age=") {
+			if seconds, err := strconv.Atoi(directive[len("max-age="):]); err == nil {
+				return clampTTL(time.Duration(seconds) * time.Second)
+			}
+		}
+	}
+
+	if expires, err := http.ParseTime(resp.Header.Get("Expires")); err == nil {
+		ttl = time.Until(expires)
+	}
+
+	return clampTTL(ttl)
+}
+
+func clampTTL(ttl time.Duration) time.Duration {
+	if ttl < minKeysTTL {
+		return minKeysTTL
+	}
+
+	return ttl
+}
@@ -0,0 +1,78 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/hugosrc/shortlink/internal/util"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// minSecretLength is the size of the HS256 output; shorter secrets are
+// easier to brute force than the signature itself.
+const minSecretLength = 32
+
+// StaticKeySet holds keys that never change, read from a local JWKS file or
+// derived from a shared secret.
+type StaticKeySet struct {
+	keys []jose.JSONWebKey
+}
+
+// LoadKeySet reads a JSON Web Key Set from path. Private keys are reduced to
+// their public half, so the file used to sign test tokens can be reused.
+// Symmetric (oct) keys are kept as they are.
+func LoadKeySet(path string) (*StaticKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "read jwks file")
+	}
+
+	var keySet jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &keySet); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "json unmarshal error")
+	}
+
+	if len(keySet.Keys) == 0 {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "jwks file %s has no keys", path)
+	}
+
+	keys := make([]jose.JSONWebKey, 0, len(keySet.Keys))
+	for _, key := range keySet.Keys {
+		if _, symmetric := key.Key.([]byte); symmetric {
+			keys = append(keys, key)
+			continue
+		}
+
+		if key = key.Public(); !key.Valid() {
+			return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "jwks file %s has an invalid key %q", path, key.KeyID)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return &StaticKeySet{keys: keys}, nil
+}
+
+// NewSecretKeySet creates a key set verifying HS256 tokens signed with
+// secret.
+func NewSecretKeySet(secret string) (*StaticKeySet, error) {
+	if len(secret) < minSecretLength {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "hs256 secret must have at least %d bytes", minSecretLength)
+	}
+
+	return &StaticKeySet{keys: []jose.JSONWebKey{{
+		Key:       []byte(secret),
+		Algorithm: string(jose.HS256),
+		Use:       "sig",
+	}}}, nil
+}
+
+// Key returns the key identified by kid.
+func (ks *StaticKeySet) Key(ctx context.Context, kid string) (*jose.JSONWebKey, error) {
+	if key := lookupKey(ks.keys, kid); key != nil {
+		return key, nil
+	}
+
+	return nil, util.NewErrorf(util.ErrCodeUnknown, "unknown signing key %q", kid)
+}
@@ -0,0 +1,24 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/spf13/viper"
+)
+
+// New connects to the database of POSTGRES_URL. Pool settings such as
+// pool_max_conns can be given as parameters of the URL.
+func New(conf *viper.Viper) (*pgxpool.Pool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.Connect(ctx, conf.GetString("POSTGRES_URL"))
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error connecting to postgres server")
+	}
+
+	return pool, nil
+}
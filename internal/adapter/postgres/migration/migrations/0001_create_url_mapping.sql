@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS url_mapping (
+  hash VARCHAR(32) PRIMARY KEY,
+  original_url TEXT NOT NULL,
+  user_id VARCHAR(64),
+  workspace_id VARCHAR(64),
+  utm JSONB,
+  forward_query BOOLEAN NOT NULL DEFAULT FALSE,
+  rules JSONB,
+  variants JSONB,
+  metadata JSONB,
+  social_card JSONB,
+  anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+  expires_at TIMESTAMPTZ,
+  creation_time TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS url_mapping_user_id_idx ON url_mapping (user_id);
+CREATE INDEX IF NOT EXISTS url_mapping_workspace_id_idx ON url_mapping (workspace_id);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS workspaces (
+  id VARCHAR(64) PRIMARY KEY,
+  name TEXT NOT NULL,
+  created_by VARCHAR(64) NOT NULL,
+  creation_time TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+  workspace_id VARCHAR(64) NOT NULL REFERENCES workspaces (id) ON DELETE CASCADE,
+  user_id VARCHAR(64) NOT NULL,
+  role VARCHAR(16) NOT NULL,
+  joined_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS workspace_members_user_id_idx ON workspace_members (user_id);
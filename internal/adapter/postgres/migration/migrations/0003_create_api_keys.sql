@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+  id VARCHAR(32) PRIMARY KEY,
+  user_id VARCHAR(64) NOT NULL,
+  name TEXT NOT NULL,
+  hash VARCHAR(64) NOT NULL UNIQUE,
+  scopes TEXT[],
+  expires_at TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ,
+  creation_time TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
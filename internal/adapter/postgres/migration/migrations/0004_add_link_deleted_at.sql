@@ -0,0 +1,4 @@
+ALTER TABLE url_mapping ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS url_mapping_trash_idx ON url_mapping (user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS url_mapping_deleted_at_idx ON url_mapping (deleted_at) WHERE deleted_at IS NOT NULL;
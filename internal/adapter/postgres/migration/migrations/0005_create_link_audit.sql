@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS link_audit (
+  id VARCHAR(36) PRIMARY KEY,
+  hash VARCHAR(32) NOT NULL,
+  action VARCHAR(16) NOT NULL,
+  actor VARCHAR(64),
+  api_key_id VARCHAR(32),
+  old_value JSONB,
+  new_value JSONB,
+  request JSONB,
+  time TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS link_audit_hash_idx ON link_audit (hash, time DESC);
+CREATE INDEX IF NOT EXISTS link_audit_time_idx ON link_audit (time DESC);
+
+CREATE OR REPLACE FUNCTION link_audit_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'link_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS link_audit_append_only ON link_audit;
+CREATE TRIGGER link_audit_append_only BEFORE UPDATE OR DELETE ON link_audit
+  FOR EACH ROW EXECUTE FUNCTION link_audit_append_only();
@@ -0,0 +1 @@
+ALTER TABLE url_mapping ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS url_mapping_user_creation_idx ON url_mapping (user_id, creation_time DESC) WHERE workspace_id IS NULL AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS url_mapping_workspace_creation_idx ON url_mapping (workspace_id, creation_time DESC) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS url_mapping_tags_idx ON url_mapping USING GIN (tags);
@@ -0,0 +1,240 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var files embed.FS
+
+// lockKey identifies the advisory lock held while migrating.
+const lockKey = 0x73686f72746c6e6b
+
+// Migration is a versioned SQL file. Files are named <version>_<name>.sql
+// and are applied in version order, each in its own transaction.
+type Migration struct {
+	Version  int
+	Name     string
+	Checksum string
+	SQL      string
+}
+
+// Migrator applies the embedded migrations and records them in the
+// schema_migrations table. Replicas starting at the same time take turns
+// through a session advisory lock.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+func New(pool *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		pool:       pool,
+		migrations: migrations,
+	}, nil
+}
+
+func load() ([]Migration, error) {
+	entries, err := files.ReadDir("migrations")
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error reading migrations")
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+
+		parts := strings.SplitN(name, "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) != 2 {
+			return nil, util.NewErrorf(util.ErrCodeUnknown, "migration %s is not named <version>_<name>.sql", entry.Name())
+		}
+
+		data, err := files.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error reading migration %s", entry.Name())
+		}
+
+		sum := sha256.Sum256(data)
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     parts[1],
+			Checksum: hex.EncodeToString(sum[:]),
+			SQL:      string(data),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, util.NewErrorf(util.ErrCodeUnknown, "duplicate migration version %d", migrations[i].Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Up applies the pending migrations and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error acquiring connection")
+	}
+	defer conn.Release()
+
+	// the lock belongs to the session, so it is taken and released on the
+	// same connection
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", int64(lockKey)); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error acquiring migration lock")
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", int64(lockKey))
+	}()
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  checksum VARCHAR(64) NOT NULL,
+  applied_at TIMESTAMPTZ NOT NULL
+)`); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error creating schema_migrations")
+	}
+
+	applied, err := appliedMigrations(ctx, conn.Conn())
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if checksum, ok := applied[migration.Version]; ok {
+			if checksum != migration.Checksum {
+				return done, util.NewErrorf(util.ErrCodeUnknown, "migration %d was changed after being applied", migration.Version)
+			}
+			continue
+		}
+
+		if err := apply(ctx, conn.Conn(), migration); err != nil {
+			return done, err
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Verify checks that every migration has been applied unchanged and that
+// the schema isn't ahead of this build.
+func (m *Migrator) Verify(ctx context.Context) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error acquiring connection")
+	}
+	defer conn.Release()
+
+	applied, err := appliedMigrations(ctx, conn.Conn())
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, migration := range m.migrations {
+		checksum, ok := applied[migration.Version]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%d_%s is pending", migration.Version, migration.Name))
+		case checksum != migration.Checksum:
+			problems = append(problems, fmt.Sprintf("%d_%s was changed after being applied", migration.Version, migration.Name))
+		}
+		delete(applied, migration.Version)
+	}
+
+	for version := range applied {
+		problems = append(problems, fmt.Sprintf("%d is applied but unknown to this build", version))
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return util.NewErrorf(util.ErrCodeUnknown, "schema is out of date: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, conn *pgx.Conn) (map[int]string, error) {
+	rows, err := conn.Query(ctx, "SELECT version, checksum FROM schema_migrations")
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving applied migrations")
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string)
+	for rows.Next() {
+		var (
+			version  int
+			checksum string
+		)
+
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving applied migrations")
+		}
+
+		applied[version] = checksum
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving applied migrations")
+	}
+
+	return applied, nil
+}
+
+func apply(ctx context.Context, conn *pgx.Conn, migration Migration) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error starting transaction")
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, migration.SQL); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "migration %d_%s", migration.Version, migration.Name)
+	}
+
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)",
+		migration.Version,
+		migration.Name,
+		migration.Checksum,
+		time.Now(),
+	); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error recording migration %d", migration.Version)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error committing migration %d", migration.Version)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const apiKeyColumns = "id, user_id, name, hash, scopes, expires_at, last_used_at, revoked_at, creation_time"
+
+type APIKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(pool *pgxpool.Pool) port.APIKeyRepository {
+	return &APIKeyRepository{
+		pool: pool,
+	}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	if _, err := r.pool.Exec(ctx,
+		"INSERT INTO api_keys (id, user_id, name, hash, scopes, expires_at, creation_time) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		key.ID, key.UserID, key.Name, key.Hash, key.Scopes, key.ExpiresAt, key.CreationTime,
+	); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting api key")
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepository) FindByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	key, err := scanAPIKey(r.pool.QueryRow(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, util.WrapErrorf(err, util.ErrCodeNotFound, "api key not found")
+		}
+
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving api key")
+	}
+
+	return key, nil
+}
+
+func (r *APIKeyRepository) ListByUser(ctx context.Context, userID string) ([]*domain.APIKey, error) {
+	rows, err := r.pool.Query(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing api keys")
+	}
+	defer rows.Close()
+
+	keys := []*domain.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing api keys")
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing api keys")
+	}
+
+	return keys, nil
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	if _, err := r.pool.Exec(ctx, "UPDATE api_keys SET revoked_at = $1 WHERE id = $2", at, id); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error revoking api key")
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepository) UpdateLastUsed(ctx context.Context, id string, at time.Time) error {
+	if _, err := r.pool.Exec(ctx, "UPDATE api_keys SET last_used_at = $1 WHERE id = $2", at, id); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error updating api key")
+	}
+
+	return nil
+}
+
+func scanAPIKey(row pgx.Row) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Hash,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreationTime,
+	); err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const auditColumns = "id, hash, action, actor, api_key_id, old_value, new_value, request, time"
+
+// AuditRepository stores events in a table that rejects updates and
+// deletes, so the trail can't be rewritten through the application.
+type AuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditRepository(pool *pgxpool.Pool) port.AuditRepository {
+	return &AuditRepository{
+		pool: pool,
+	}
+}
+
+func (r *AuditRepository) Append(ctx context.Context, event *domain.AuditEvent) error {
+	before, err := marshalJSON(event.Before)
+	if err != nil {
+		return err
+	}
+
+	after, err := marshalJSON(event.After)
+	if err != nil {
+		return err
+	}
+
+	request, err := marshalJSON(event.Request)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.pool.Exec(ctx,
+		"INSERT INTO link_audit ("+auditColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		event.ID, event.Hash, event.Action, nullable(event.Actor), nullable(event.APIKeyID), before, after, request, event.Time,
+	); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting audit event")
+	}
+
+	return nil
+}
+
+func (r *AuditRepository) ListByHash(ctx context.Context, hash string, limit int) ([]*domain.AuditEvent, error) {
+	return r.Query(ctx, &domain.AuditFilter{Hash: hash, Limit: limit})
+}
+
+func (r *AuditRepository) Query(ctx context.Context, filter *domain.AuditFilter) ([]*domain.AuditEvent, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if len(filter.Hash) > 0 {
+		where("hash = $%d", filter.Hash)
+	}
+
+	if len(filter.Actor) > 0 {
+		where("actor = $%d", filter.Actor)
+	}
+
+	if len(filter.Action) > 0 {
+		where("action = $%d", filter.Action)
+	}
+
+	if !filter.Since.IsZero() {
+		where("time >= $%d", filter.Since)
+	}
+
+	if !filter.Until.IsZero() {
+		where("time < $%d", filter.Until)
+	}
+
+	query := "SELECT " + auditColumns + " FROM link_audit"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY time DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing audit events")
+	}
+	defer rows.Close()
+
+	events := []*domain.AuditEvent{}
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing audit events")
+	}
+
+	return events, nil
+}
+
+func scanAuditEvent(row pgx.Row) (*domain.AuditEvent, error) {
+	var (
+		event                   domain.AuditEvent
+		actor, apiKeyID         *string
+		before, after, metadata []byte
+	)
+
+	if err := row.Scan(
+		&event.ID,
+		&event.Hash,
+		&event.Action,
+		&actor,
+		&apiKeyID,
+		&before,
+		&after,
+		&metadata,
+		&event.Time,
+	); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing audit events")
+	}
+
+	if actor != nil {
+		event.Actor = *actor
+	}
+
+	if apiKeyID != nil {
+		event.APIKeyID = *apiKeyID
+	}
+
+	if err := unmarshalJSON(before, &event.Before); err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalJSON(after, &event.After); err != nil {
+		return nil, err
+	}
+
+	if err := unmarshalJSON(metadata, &event.Request); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
@@ -0,0 +1,353 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// uniqueViolation is the SQLSTATE of duplicate keys.
+const uniqueViolation = "23505"
+
+const linkColumns = "hash, original_url, user_id, workspace_id, utm, forward_query, rules, variants, metadata, social_card, title, description, tags, notes, disabled, anonymous, expires_at, deleted_at, creation_time, version"
+
+type LinkRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewLinkRepository(pool *pgxpool.Pool) port.LinkRepository {
+	return &LinkRepository{
+		pool: pool,
+	}
+}
+
+func (r *LinkRepository) Create(ctx context.Context, link *domain.Link) error {
+	utm, err := marshalJSON(link.UTM)
+	if err != nil {
+		return err
+	}
+
+	rules, err := marshalJSON(link.Rules)
+	if err != nil {
+		return err
+	}
+
+	variants, err := marshalJSON(link.Variants)
+	if err != nil {
+		return err
+	}
+
+	socialCard, err := marshalJSON(link.SocialCard)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.pool.Exec(ctx,
+		"INSERT INTO url_mapping (hash, original_url, user_id, workspace_id, utm, forward_query, rules, variants, social_card, title, description, tags, notes, disabled, anonymous, expires_at, creation_time, version) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)",
+		link.Hash,
+		link.OriginalURL,
+		nullable(link.UserID),
+		nullable(link.WorkspaceID),
+		utm,
+		link.ForwardQuery,
+		rules,
+		variants,
+		socialCard,
+		link.Title,
+		link.Description,
+		link.Tags,
+		link.Notes,
+		link.Disabled,
+		link.Anonymous,
+		link.ExpiresAt,
+		link.CreationTime,
+		link.Version,
+	); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return util.WrapErrorf(err, util.ErrCodeInvalidArgument, "hash %s is already in use", link.Hash)
+		}
+
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting url")
+	}
+
+	return nil
+}
+
+// Delete removes the link permanently.
+func (r *LinkRepository) Delete(ctx context.Context, hash string) error {
+	if _, err := r.pool.Exec(ctx, "DELETE FROM url_mapping WHERE hash = $1", hash); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting url")
+	}
+
+	return nil
+}
+
+func (r *LinkRepository) FindByHash(ctx context.Context, hash string) (*domain.Link, error) {
+	link, err := scanLink(r.pool.QueryRow(ctx, "SELECT "+linkColumns+" FROM url_mapping WHERE hash = $1", hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, util.WrapErrorf(err, util.ErrCodeNotFound, "url not found")
+		}
+
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving url")
+	}
+
+	return link, nil
+}
+
+func (r *LinkRepository) Update(ctx context.Context, link *domain.Link) error {
+	utm, err := marshalJSON(link.UTM)
+	if err != nil {
+		return err
+	}
+
+	rules, err := marshalJSON(link.Rules)
+	if err != nil {
+		return err
+	}
+
+	variants, err := marshalJSON(link.Variants)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := marshalJSON(link.Metadata)
+	if err != nil {
+		return err
+	}
+
+	socialCard, err := marshalJSON(link.SocialCard)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.pool.Exec(ctx,
+		"UPDATE url_mapping SET original_url = $1, utm = $2, forward_query = $3, rules = $4, variants = $5, metadata = $6, social_card = $7, title = $8, description = $9, tags = $10, notes = $11, disabled = $12, version = version + 1 WHERE hash = $13 AND version = $14",
+		link.OriginalURL,
+		utm,
+		link.ForwardQuery,
+		rules,
+		variants,
+		metadata,
+		socialCard,
+		link.Title,
+		link.Description,
+		link.Tags,
+		link.Notes,
+		link.Disabled,
+		link.Hash,
+		link.Version,
+	)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error updating url")
+	}
+
+	return r.versioned(ctx, link, tag)
+}
+
+func (r *LinkRepository) UpdateMetadata(ctx context.Context, link *domain.Link, metadata *domain.LinkMetadata) error {
+	value, err := marshalJSON(metadata)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.pool.Exec(ctx,
+		"UPDATE url_mapping SET metadata = $1 WHERE hash = $2 AND version = $3",
+		value, link.Hash, link.Version)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error updating url metadata")
+	}
+
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	return r.versioned(ctx, link, tag)
+}
+
+func (r *LinkRepository) SoftDelete(ctx context.Context, link *domain.Link, at time.Time) error {
+	tag, err := r.pool.Exec(ctx,
+		"UPDATE url_mapping SET deleted_at = $1, version = version + 1 WHERE hash = $2 AND version = $3",
+		at, link.Hash, link.Version)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting url")
+	}
+
+	return r.versioned(ctx, link, tag)
+}
+
+func (r *LinkRepository) Restore(ctx context.Context, link *domain.Link) error {
+	tag, err := r.pool.Exec(ctx,
+		"UPDATE url_mapping SET deleted_at = NULL, version = version + 1 WHERE hash = $1 AND version = $2",
+		link.Hash, link.Version)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error restoring url")
+	}
+
+	return r.versioned(ctx, link, tag)
+}
+
+func (r *LinkRepository) Purge(ctx context.Context, link *domain.Link) error {
+	tag, err := r.pool.Exec(ctx, "DELETE FROM url_mapping WHERE hash = $1 AND version = $2", link.Hash, link.Version)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting url")
+	}
+
+	return r.versioned(ctx, link, tag)
+}
+
+// versioned increments the version of the link once a conditional update
+// applied, or reports why it didn't.
+func (r *LinkRepository) versioned(ctx context.Context, link *domain.Link, tag pgconn.CommandTag) error {
+	if tag.RowsAffected() > 0 {
+		link.Version++
+		return nil
+	}
+
+	var version int64
+	if err := r.pool.QueryRow(ctx, "SELECT version FROM url_mapping WHERE hash = $1", link.Hash).Scan(&version); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return util.WrapErrorf(err, util.ErrCodeNotFound, "url not found")
+		}
+
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving url")
+	}
+
+	return util.NewErrorf(util.ErrCodePreconditionFailed, "url was modified, current version is %d", version)
+}
+
+func (r *LinkRepository) ListDeleted(ctx context.Context, userID string) ([]*domain.Link, error) {
+	links, err := r.list(ctx,
+		"SELECT "+linkColumns+" FROM url_mapping WHERE user_id = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC", userID)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing deleted urls")
+	}
+
+	return links, nil
+}
+
+func (r *LinkRepository) ListDeletedBefore(ctx context.Context, before time.Time) ([]*domain.Link, error) {
+	links, err := r.list(ctx,
+		"SELECT "+linkColumns+" FROM url_mapping WHERE deleted_at < $1", before)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing deleted urls")
+	}
+
+	return links, nil
+}
+
+func (r *LinkRepository) Each(ctx context.Context, fn func(link *domain.Link) error) error {
+	rows, err := r.pool.Query(ctx, "SELECT "+linkColumns+" FROM url_mapping")
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error listing urls")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return util.WrapErrorf(err, util.ErrCodeUnknown, "error listing urls")
+		}
+
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error listing urls")
+	}
+
+	return nil
+}
+
+func (r *LinkRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.Link, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*domain.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+func scanLink(row pgx.Row) (*domain.Link, error) {
+	var (
+		link        domain.Link
+		userID      *string
+		workspaceID *string
+		utm         []byte
+		rules       []byte
+		variants    []byte
+		metadata    []byte
+		socialCard  []byte
+	)
+
+	if err := row.Scan(
+		&link.Hash,
+		&link.OriginalURL,
+		&userID,
+		&workspaceID,
+		&utm,
+		&link.ForwardQuery,
+		&rules,
+		&variants,
+		&metadata,
+		&socialCard,
+		&link.Title,
+		&link.Description,
+		&link.Tags,
+		&link.Notes,
+		&link.Disabled,
+		&link.Anonymous,
+		&link.ExpiresAt,
+		&link.DeletedAt,
+		&link.CreationTime,
+		&link.Version,
+	); err != nil {
+		return nil, err
+	}
+
+	if userID != nil {
+		link.UserID = *userID
+	}
+
+	if workspaceID != nil {
+		link.WorkspaceID = *workspaceID
+	}
+
+	for _, column := range []struct {
+		data []byte
+		v    interface{}
+	}{
+		{utm, &link.UTM},
+		{rules, &link.Rules},
+		{variants, &link.Variants},
+		{metadata, &link.Metadata},
+		{socialCard, &link.SocialCard},
+	} {
+		if err := unmarshalJSON(column.data, column.v); err != nil {
+			return nil, err
+		}
+	}
+
+	return &link, nil
+}
@@ -0,0 +1,48 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/adapter/postgres/migration"
+	"github.com/hugosrc/shortlink/internal/adapter/postgres/repository"
+	"github.com/hugosrc/shortlink/internal/core/port/porttest"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// TestRepositories migrates the database of POSTGRES_DSN, which should be
+// a disposable one, and runs the repository checks against it.
+func TestRepositories(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_DSN")
+	if len(dsn) == 0 {
+		t.Skip("POSTGRES_DSN is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	migrator, err := migration.New(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	porttest.Run(t, porttest.Repositories{
+		Links:      repository.NewLinkRepository(pool),
+		Workspaces: repository.NewWorkspaceRepository(pool),
+		APIKeys:    repository.NewAPIKeyRepository(pool),
+		Audit:      repository.NewAuditRepository(pool),
+		Search:     repository.NewLinkIndex(pool),
+	})
+}
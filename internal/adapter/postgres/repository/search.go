@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// linkHost extracts the lowercase host of the destination url.
+const linkHost = `lower(substring(original_url from '^[a-zA-Z][a-zA-Z0-9+.-]*://(?:[^@/?#]*@)?([^:/?#]+)'))`
+
+// LinkIndex searches url_mapping itself, which is indexed by owner and
+// creation time and by tags, so there is nothing to keep up to date.
+type LinkIndex struct {
+	pool *pgxpool.Pool
+}
+
+func NewLinkIndex(pool *pgxpool.Pool) port.LinkIndex {
+	return &LinkIndex{
+		pool: pool,
+	}
+}
+
+func (i *LinkIndex) Index(ctx context.Context, link *domain.Link) error {
+	return nil
+}
+
+func (i *LinkIndex) Remove(ctx context.Context, link *domain.Link) error {
+	return nil
+}
+
+func (i *LinkIndex) Clear(ctx context.Context, owner string) error {
+	return nil
+}
+
+func (i *LinkIndex) Search(ctx context.Context, query *domain.LinkQuery) ([]string, error) {
+	var (
+		conditions = []string{"deleted_at IS NULL", "NOT anonymous"}
+		args       []interface{}
+	)
+
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "$?", fmt.Sprintf("$%d", len(args))))
+	}
+
+	if len(query.WorkspaceID) > 0 {
+		where("workspace_id = $?", query.WorkspaceID)
+	} else {
+		where("user_id = $? AND workspace_id IS NULL", query.UserID)
+	}
+
+	if len(query.Tags) > 0 {
+		where("tags @> $?", query.Tags)
+	}
+
+	if len(query.Domain) > 0 {
+		where("("+linkHost+" = $? OR right("+linkHost+", length($?) + 1) = '.' || $?)", query.Domain)
+	}
+
+	if len(query.Title) > 0 {
+		where("strpos(lower(title), lower($?)) > 0", query.Title)
+	}
+
+	if !query.Since.IsZero() {
+		where("creation_time >= $?", query.Since)
+	}
+
+	if !query.Until.IsZero() {
+		where("creation_time < $?", query.Until)
+	}
+
+	args = append(args, query.Limit)
+	stmt := fmt.Sprintf(
+		"SELECT hash FROM url_mapping WHERE %s ORDER BY creation_time DESC, hash LIMIT $%d",
+		strings.Join(conditions, " AND "), len(args),
+	)
+
+	rows, err := i.pool.Query(ctx, stmt, args...)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error searching urls")
+	}
+	defer rows.Close()
+
+	hashes := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error searching urls")
+		}
+
+		hashes = append(hashes, hash)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error searching urls")
+	}
+
+	return hashes, nil
+}
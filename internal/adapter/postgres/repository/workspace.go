@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type WorkspaceRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWorkspaceRepository(pool *pgxpool.Pool) port.WorkspaceRepository {
+	return &WorkspaceRepository{
+		pool: pool,
+	}
+}
+
+// Create inserts the workspace and its owner in a single transaction.
+func (r *WorkspaceRepository) Create(ctx context.Context, workspace *domain.Workspace, owner *domain.Member) error {
+	err := r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO workspaces (id, name, created_by, creation_time) VALUES ($1, $2, $3, $4)",
+			workspace.ID,
+			workspace.Name,
+			workspace.CreatedBy,
+			workspace.CreationTime,
+		); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx,
+			"INSERT INTO workspace_members (workspace_id, user_id, role, joined_at) VALUES ($1, $2, $3, $4)",
+			owner.WorkspaceID,
+			owner.UserID,
+			owner.Role,
+			owner.JoinedAt,
+		)
+		return err
+	})
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting workspace")
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) FindByID(ctx context.Context, id string) (*domain.Workspace, error) {
+	var workspace domain.Workspace
+	if err := r.pool.QueryRow(ctx,
+		"SELECT id, name, created_by, creation_time FROM workspaces WHERE id = $1", id,
+	).Scan(
+		&workspace.ID,
+		&workspace.Name,
+		&workspace.CreatedBy,
+		&workspace.CreationTime,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, util.WrapErrorf(err, util.ErrCodeNotFound, "workspace not found")
+		}
+
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving workspace")
+	}
+
+	return &workspace, nil
+}
+
+func (r *WorkspaceRepository) FindMember(ctx context.Context, workspaceID string, userID string) (*domain.Member, error) {
+	member := domain.Member{WorkspaceID: workspaceID, UserID: userID}
+	if err := r.pool.QueryRow(ctx,
+		"SELECT role, joined_at FROM workspace_members WHERE workspace_id = $1 AND user_id = $2",
+		workspaceID,
+		userID,
+	).Scan(
+		&member.Role,
+		&member.JoinedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, util.WrapErrorf(err, util.ErrCodeNotFound, "member not found")
+		}
+
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving member")
+	}
+
+	return &member, nil
+}
+
+func (r *WorkspaceRepository) ListMembers(ctx context.Context, workspaceID string) ([]*domain.Member, error) {
+	members, err := r.listMembers(ctx,
+		"SELECT workspace_id, user_id, role, joined_at FROM workspace_members WHERE workspace_id = $1 ORDER BY user_id", workspaceID)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing members")
+	}
+
+	return members, nil
+}
+
+func (r *WorkspaceRepository) ListByUser(ctx context.Context, userID string) ([]*domain.Member, error) {
+	members, err := r.listMembers(ctx,
+		"SELECT workspace_id, user_id, role, joined_at FROM workspace_members WHERE user_id = $1 ORDER BY workspace_id", userID)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error listing workspaces")
+	}
+
+	return members, nil
+}
+
+func (r *WorkspaceRepository) listMembers(ctx context.Context, query string, arg string) ([]*domain.Member, error) {
+	rows, err := r.pool.Query(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*domain.Member
+	for rows.Next() {
+		var member domain.Member
+		if err := rows.Scan(&member.WorkspaceID, &member.UserID, &member.Role, &member.JoinedAt); err != nil {
+			return nil, err
+		}
+
+		members = append(members, &member)
+	}
+
+	return members, rows.Err()
+}
+
+func (r *WorkspaceRepository) SaveMember(ctx context.Context, member *domain.Member) error {
+	if _, err := r.pool.Exec(ctx,
+		"INSERT INTO workspace_members (workspace_id, user_id, role, joined_at) VALUES ($1, $2, $3, $4) ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = EXCLUDED.role, joined_at = EXCLUDED.joined_at",
+		member.WorkspaceID,
+		member.UserID,
+		member.Role,
+		member.JoinedAt,
+	); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error saving member")
+	}
+
+	return nil
+}
+
+func (r *WorkspaceRepository) DeleteMember(ctx context.Context, workspaceID string, userID string) error {
+	if _, err := r.pool.Exec(ctx,
+		"DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2",
+		workspaceID,
+		userID,
+	); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error deleting member")
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package preview
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/spf13/viper"
+	"golang.org/x/net/html"
+)
+
+const (
+	maxRedirects = 5
+
+	// Defaults of PREVIEW_FETCH_TIMEOUT and PREVIEW_MAX_BYTES.
+	defaultTimeout  = 5 * time.Second
+	defaultMaxBytes = 1 << 20
+)
+
+// HTTPFetcher downloads destination pages and extracts their preview
+// metadata. Connections to loopback, private and otherwise non-public
+// addresses are refused, including those reached through redirects.
+type HTTPFetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+func NewHTTPFetcher(conf *viper.Viper) *HTTPFetcher {
+	timeout := conf.GetDuration("PREVIEW_FETCH_TIMEOUT")
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	maxBytes := conf.GetInt64("PREVIEW_MAX_BYTES")
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBytes
+	}
+
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !util.IsPublicIP(ip) {
+				return util.NewErrorf(util.ErrCodeInvalidArgument, "destination address %s is not allowed", host)
+			}
+
+			return nil
+		},
+	}
+
+	return &HTTPFetcher{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   5 * time.Second,
+				ResponseHeaderTimeout: 5 * time.Second,
+				MaxIdleConns:          10,
+				IdleConnTimeout:       30 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return util.NewErrorf(util.ErrCodeUnknown, "stopped after %d redirects", maxRedirects)
+				}
+
+				return checkScheme(req.URL)
+			},
+		},
+		maxBytes: maxBytes,
+	}
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (*domain.LinkMetadata, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "invalid url")
+	}
+
+	if err := checkScheme(target); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error creating request")
+	}
+
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "ShortlinkPreview/1.0")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error during http request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, util.NewErrorf(util.ErrCodeUnknown, "unexpected status code %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, util.NewErrorf(util.ErrCodeUnknown, "unsupported content type %q", mediaType)
+	}
+
+	metadata := parse(io.LimitReader(resp.Body, f.maxBytes), resp.Request.URL)
+	metadata.FetchedAt = time.Now()
+
+	return metadata, nil
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "unsupported scheme %q", u.Scheme)
+	}
+
+	return nil
+}
+
+// parse reads the document head, preferring Open Graph properties over the
+// standard title and description tags.
+func parse(r io.Reader, base *url.URL) *domain.LinkMetadata {
+	var (
+		metadata domain.LinkMetadata
+		title    string
+		desc     string
+		inTitle  bool
+	)
+
+	tokenizer := html.NewTokenizer(r)
+
+loop:
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			break loop
+		case html.TextToken:
+			if inTitle && len(title) == 0 {
+				title = strings.TrimSpace(string(tokenizer.Text()))
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break loop
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			attrs := map[string]string{}
+			for hasAttr {
+				var key, value []byte
+				key, value, hasAttr = tokenizer.TagAttr()
+				attrs[string(key)] = string(value)
+			}
+
+			switch string(name) {
+			case "title":
+				inTitle = true
+			case "body":
+				break loop
+			case "meta":
+				key := attrs["property"]
+				if len(key) == 0 {
+					key = attrs["name"]
+				}
+
+				content := strings.TrimSpace(attrs["content"])
+				switch strings.ToLower(key) {
+				case "og:title":
+					metadata.Title = content
+				case "og:description":
+					metadata.Description = content
+				case "description":
+					desc = content
+				case "og:image", "og:image:url", "twitter:image":
+					if len(metadata.Image) == 0 {
+						metadata.Image = resolve(base, content)
+					}
+				}
+			case "link":
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					if (rel == "icon" || rel == "apple-touch-icon") && len(metadata.Favicon) == 0 {
+						metadata.Favicon = resolve(base, attrs["href"])
+					}
+				}
+			}
+		}
+	}
+
+	if len(metadata.Title) == 0 {
+		metadata.Title = title
+	}
+
+	if len(metadata.Description) == 0 {
+		metadata.Description = desc
+	}
+
+	if len(metadata.Favicon) == 0 {
+		metadata.Favicon = resolve(base, "/favicon.ico")
+	}
+
+	return &metadata
+}
+
+func resolve(base *url.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+
+	return u.String()
+}
@@ -0,0 +1,119 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+	"rsc.io/qr"
+)
+
+var levels = map[string]qr.Level{
+	domain.QRCodeLevelLow:      qr.L,
+	domain.QRCodeLevelMedium:   qr.M,
+	domain.QRCodeLevelQuartile: qr.Q,
+	domain.QRCodeLevelHigh:     qr.H,
+}
+
+type Generator struct{}
+
+func NewGenerator() *Generator {
+	return &Generator{}
+}
+
+func (g *Generator) PNG(content string, opts *domain.QRCodeOptions) ([]byte, error) {
+	code, err := encode(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := code.Size + 2*opts.Margin
+	scale := scaleFor(modules, opts.Size)
+
+	img := image.NewPaletted(
+		image.Rect(0, 0, modules*scale, modules*scale),
+		color.Palette{opts.Background, opts.Foreground},
+	)
+
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if !code.Black(x, y) {
+				continue
+			}
+
+			for dy := 0; dy < scale; dy++ {
+				offset := img.PixOffset((x+opts.Margin)*scale, (y+opts.Margin)*scale+dy)
+				for dx := 0; dx < scale; dx++ {
+					img.Pix[offset+dx] = 1
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error encoding png")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (g *Generator) SVG(content string, opts *domain.QRCodeOptions) ([]byte, error) {
+	code, err := encode(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := code.Size + 2*opts.Margin
+	size := modules * scaleFor(modules, opts.Size)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, hex(opts.Background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hex(opts.Foreground))
+
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Black(x, y) {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+opts.Margin, y+opts.Margin)
+			}
+		}
+	}
+
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
+
+func encode(content string, opts *domain.QRCodeOptions) (*qr.Code, error) {
+	level, ok := levels[opts.Level]
+	if !ok {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "unknown error correction level %q", opts.Level)
+	}
+
+	code, err := qr.Encode(content, level)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error encoding qr code")
+	}
+
+	return code, nil
+}
+
+// scaleFor returns the largest whole number of pixels per module that fits
+// the requested size, so modules are never blurred by resampling.
+func scaleFor(modules int, size int) int {
+	if scale := size / modules; scale > 1 {
+		return scale
+	}
+
+	return 1
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
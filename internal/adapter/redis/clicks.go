@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const clicksKeyPrefix = "clicks:"
+
+type RedisClickCounter struct {
+	rdb *redis.Client
+}
+
+func NewRedisClickCounter(rdb *redis.Client) *RedisClickCounter {
+	return &RedisClickCounter{
+		rdb: rdb,
+	}
+}
+
+func (c *RedisClickCounter) Incr(ctx context.Context, hash string) error {
+	if err := c.rdb.Incr(ctx, clicksKeyPrefix+hash).Err(); err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "error incrementing clicks")
+	}
+
+	return nil
+}
+
+func (c *RedisClickCounter) Count(ctx context.Context, hash string) (int64, error) {
+	count, err := c.rdb.Get(ctx, clicksKeyPrefix+hash).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving clicks")
+	}
+
+	return count, nil
+}
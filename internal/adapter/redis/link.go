@@ -2,9 +2,11 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 
 	"github.com/go-redis/redis/v9"
+	"github.com/hugosrc/shortlink/internal/core/domain"
 	"github.com/hugosrc/shortlink/internal/util"
 )
 
@@ -18,17 +20,31 @@ func NewRedisCaching(rdb *redis.Client) *RedisCaching {
 	}
 }
 
-func (c *RedisCaching) Get(ctx context.Context, hash string) (string, error) {
-	url, err := c.rdb.Get(ctx, hash).Result()
-	if err != nil && !errors.Is(err, redis.Nil) {
-		return "", util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving data")
+func (c *RedisCaching) Get(ctx context.Context, hash string) (*domain.Link, error) {
+	data, err := c.rdb.Get(ctx, hash).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error retrieving data")
+	}
+
+	var link domain.Link
+	if err := json.Unmarshal(data, &link); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
 	}
 
-	return url, nil
+	return &link, nil
 }
 
-func (c *RedisCaching) Set(ctx context.Context, hash string, originalURL string) error {
-	if err := c.rdb.Set(ctx, hash, originalURL, 0).Err(); err != nil {
+func (c *RedisCaching) Set(ctx context.Context, link *domain.Link) error {
+	data, err := json.Marshal(link)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeUnknown, "json marshal error")
+	}
+
+	if err := c.rdb.Set(ctx, link.Hash, data, 0).Err(); err != nil {
 		return util.WrapErrorf(err, util.ErrCodeUnknown, "error inserting data")
 	}
 
@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const rateLimitKeyPrefix = "ratelimit:"
+
+// gcraScript implements the generic cell rate algorithm atomically. Only the
+// theoretical arrival time (TAT) of the key is stored, in microseconds of
+// the Redis clock so every instance of the service agrees on the time.
+//
+// KEYS[1] is the key, ARGV[1] the emission interval and ARGV[2] the burst
+// tolerance, both in microseconds. It returns whether the request is
+// allowed, the remaining requests, and the reset and retry times in
+// microseconds.
+var gcraScript = redis.NewScript(`
+redis.replicate_commands()
+
+local interval = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+
+local new_tat = tat + interval
+local allow_at = new_tat - tolerance
+
+if now < allow_at then
+	return {0, 0, tat - now, allow_at - now}
+end
+
+redis.call('SET', KEYS[1], new_tat, 'PX', math.ceil((new_tat - now) / 1000))
+
+return {1, math.floor((now - allow_at) / interval), new_tat - now, 0}
+`)
+
+// RedisRateLimiter shares rate limits between instances of the service.
+// While Redis is unavailable requests are counted by the fallback limiter
+// instead, which is usually local to the instance.
+type RedisRateLimiter struct {
+	rdb      *redis.Client
+	fallback port.RateLimiter
+}
+
+func NewRedisRateLimiter(rdb *redis.Client, fallback port.RateLimiter) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		rdb:      rdb,
+		fallback: fallback,
+	}
+}
+
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string, limit domain.RateLimit) (*domain.RateLimitDecision, error) {
+	if limit.Limit <= 0 || limit.Period <= 0 {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "invalid rate limit %d/%s", limit.Limit, limit.Period)
+	}
+
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Limit
+	}
+
+	interval := limit.Period / time.Duration(limit.Limit)
+	tolerance := interval * time.Duration(burst)
+
+	result, err := gcraScript.Run(ctx, l.rdb, []string{rateLimitKeyPrefix + key},
+		interval.Microseconds(), tolerance.Microseconds()).Int64Slice()
+	if err != nil {
+		if l.fallback != nil {
+			return l.fallback.Allow(ctx, key, limit)
+		}
+
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error checking rate limit")
+	}
+
+	return &domain.RateLimitDecision{
+		Allowed:    result[0] == 1,
+		Limit:      burst,
+		Remaining:  int(result[1]),
+		ResetAfter: time.Duration(result[2]) * time.Microsecond,
+		RetryAfter: time.Duration(result[3]) * time.Microsecond,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const replayKeyPrefix = "replay:"
+
+// RedisReplayGuard shares the claimed tokens between instances of the
+// service, each stored with SET NX until it expires. While Redis is
+// unavailable tokens are claimed in the fallback guard instead.
+type RedisReplayGuard struct {
+	rdb      *redis.Client
+	fallback port.ReplayGuard
+}
+
+func NewRedisReplayGuard(rdb *redis.Client, fallback port.ReplayGuard) *RedisReplayGuard {
+	return &RedisReplayGuard{
+		rdb:      rdb,
+		fallback: fallback,
+	}
+}
+
+func (g *RedisReplayGuard) Claim(ctx context.Context, token string, ttl time.Duration) (bool, error) {
+	claimed, err := g.rdb.SetNX(ctx, replayKeyPrefix+token, 1, ttl).Result()
+	if err != nil {
+		if g.fallback != nil {
+			return g.fallback.Claim(ctx, token, ttl)
+		}
+
+		return false, util.WrapErrorf(err, util.ErrCodeUnknown, "error claiming token")
+	}
+
+	return claimed, nil
+}
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/hugosrc/shortlink/internal/adapter/bolt"
+	"github.com/hugosrc/shortlink/internal/adapter/cassandra"
+	cassandraMigration "github.com/hugosrc/shortlink/internal/adapter/cassandra/migration"
+	cassandraRepository "github.com/hugosrc/shortlink/internal/adapter/cassandra/repository"
+	"github.com/hugosrc/shortlink/internal/adapter/postgres"
+	postgresMigration "github.com/hugosrc/shortlink/internal/adapter/postgres/migration"
+	postgresRepository "github.com/hugosrc/shortlink/internal/adapter/postgres/repository"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/spf13/viper"
+	"go.etcd.io/bbolt"
+)
+
+// Storage backends selected with STORAGE_BACKEND.
+const (
+	Cassandra = "cassandra"
+	Postgres  = "postgres"
+	Bolt      = "bolt"
+)
+
+// Storage holds the repositories of the configured backend.
+type Storage struct {
+	Links      port.LinkRepository
+	Workspaces port.WorkspaceRepository
+	APIKeys    port.APIKeyRepository
+	Audit      port.AuditRepository
+	Search     port.LinkIndex
+
+	close func()
+}
+
+// Migration is a schema migration applied to the backend.
+type Migration struct {
+	Version int
+	Name    string
+}
+
+// Backend returns the backend of STORAGE_BACKEND, cassandra by default.
+// The standalone mode always uses bolt.
+func Backend(conf *viper.Viper) (string, error) {
+	switch backend := conf.GetString("STORAGE_BACKEND"); backend {
+	case "", Cassandra:
+		return Cassandra, nil
+	case Postgres, Bolt:
+		return backend, nil
+	default:
+		return "", util.NewErrorf(util.ErrCodeInvalidArgument, "unknown storage backend %q", backend)
+	}
+}
+
+// Open connects to the configured backend.
+func Open(conf *viper.Viper) (*Storage, error) {
+	backend, err := Backend(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	switch backend {
+	case Bolt:
+		db, err := bolt.New(conf)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewBolt(db), nil
+	case Postgres:
+		pool, err := postgres.New(conf)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Storage{
+			Links:      postgresRepository.NewLinkRepository(pool),
+			Workspaces: postgresRepository.NewWorkspaceRepository(pool),
+			APIKeys:    postgresRepository.NewAPIKeyRepository(pool),
+			Audit:      postgresRepository.NewAuditRepository(pool),
+			Search:     postgresRepository.NewLinkIndex(pool),
+			close:      pool.Close,
+		}, nil
+	default:
+		cluster, err := cassandra.NewCluster(conf)
+		if err != nil {
+			return nil, err
+		}
+
+		opts, err := cassandra.NewQueryOptions(conf)
+		if err != nil {
+			return nil, err
+		}
+
+		session, err := cluster.CreateSession()
+		if err != nil {
+			return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "error connecting to cassandra server")
+		}
+
+		return &Storage{
+			Links:      cassandraRepository.NewLinkRepository(session, opts),
+			Workspaces: cassandraRepository.NewWorkspaceRepository(session, opts),
+			APIKeys:    cassandraRepository.NewAPIKeyRepository(session, opts),
+			Audit:      cassandraRepository.NewAuditRepository(session, opts),
+			Search:     cassandraRepository.NewLinkIndex(session, opts),
+			close:      session.Close,
+		}, nil
+	}
+}
+
+// NewBolt uses the repositories of an open bolt database, which is closed
+// with the storage.
+func NewBolt(db *bbolt.DB) *Storage {
+	return &Storage{
+		Links:      bolt.NewLinkRepository(db),
+		Workspaces: bolt.NewWorkspaceRepository(db),
+		APIKeys:    bolt.NewAPIKeyRepository(db),
+		Audit:      bolt.NewAuditRepository(db),
+		Search:     bolt.NewLinkIndex(db),
+		close:      func() { _ = db.Close() },
+	}
+}
+
+func (s *Storage) Close() {
+	s.close()
+}
+
+// MigrateOnStartup reports whether the server migrates the schema of the
+// configured backend when it starts.
+func MigrateOnStartup(conf *viper.Viper) bool {
+	switch backend, _ := Backend(conf); backend {
+	case Cassandra:
+		return conf.GetBool("CASSANDRA_MIGRATE_ON_STARTUP")
+	case Postgres:
+		return conf.GetBool("POSTGRES_MIGRATE_ON_STARTUP")
+	default:
+		return false
+	}
+}
+
+// Migrate applies the pending migrations of the configured backend and
+// returns them, including those applied before a failure. Bolt databases
+// have no schema besides their buckets, created when they are opened.
+func Migrate(ctx context.Context, conf *viper.Viper) ([]Migration, error) {
+	backend, err := Backend(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	switch backend {
+	case Bolt:
+		return nil, nil
+	case Postgres:
+		pool, err := postgres.New(conf)
+		if err != nil {
+			return nil, err
+		}
+		defer pool.Close()
+
+		migrator, err := postgresMigration.New(pool)
+		if err != nil {
+			return nil, err
+		}
+
+		applied, err := migrator.Up(ctx)
+		migrations := make([]Migration, 0, len(applied))
+		for _, m := range applied {
+			migrations = append(migrations, Migration{Version: m.Version, Name: m.Name})
+		}
+
+		return migrations, err
+	}
+
+	cluster, err := cassandra.NewCluster(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := cassandraMigration.New(cluster, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := migrator.Up(ctx)
+	migrations := make([]Migration, 0, len(applied))
+	for _, m := range applied {
+		migrations = append(migrations, Migration{Version: m.Version, Name: m.Name})
+	}
+
+	return migrations, err
+}
+
+// Verify checks that the schema of the configured backend is up to date.
+func Verify(ctx context.Context, conf *viper.Viper) error {
+	backend, err := Backend(conf)
+	if err != nil {
+		return err
+	}
+
+	switch backend {
+	case Bolt:
+		return nil
+	case Postgres:
+		pool, err := postgres.New(conf)
+		if err != nil {
+			return err
+		}
+		defer pool.Close()
+
+		migrator, err := postgresMigration.New(pool)
+		if err != nil {
+			return err
+		}
+
+		return migrator.Verify(ctx)
+	}
+
+	cluster, err := cassandra.NewCluster(conf)
+	if err != nil {
+		return err
+	}
+
+	migrator, err := cassandraMigration.New(cluster, conf)
+	if err != nil {
+		return err
+	}
+
+	return migrator.Verify(ctx)
+}
@@ -0,0 +1,119 @@
+package urlscan
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/hugosrc/shortlink/internal/util"
+	"github.com/spf13/viper"
+)
+
+const maxURLLength = 2048
+
+// resolver looks up the addresses of hosts, like net.Resolver.
+type resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// LocalScanner rejects destinations without contacting any reputation
+// service: URLs that aren't plain http(s), embed credentials, point to
+// private networks, to the shortener itself or to a blocked host. Host names
+// are resolved and rejected if any of their addresses is private or if they
+// don't resolve. Records can still change after the check, so whatever
+// fetches destinations must check the addresses it connects to again.
+type LocalScanner struct {
+	blocked  []string
+	resolver resolver
+}
+
+// NewLocalScanner blocks the hosts listed in URLSCAN_BLOCKED_HOSTS, with
+// their subdomains, and the host of SHORTLINK_BASE_URL so short links can't
+// be chained.
+func NewLocalScanner(config *viper.Viper) *LocalScanner {
+	blocked := strings.FieldsFunc(config.GetString("URLSCAN_BLOCKED_HOSTS"), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	if base, err := url.Parse(config.GetString("SHORTLINK_BASE_URL")); err == nil && len(base.Hostname()) > 0 {
+		blocked = append(blocked, base.Hostname())
+	}
+
+	for i := range blocked {
+		blocked[i] = strings.ToLower(strings.TrimPrefix(blocked[i], "."))
+	}
+
+	return &LocalScanner{
+		blocked:  blocked,
+		resolver: net.DefaultResolver,
+	}
+}
+
+func (s *LocalScanner) Scan(ctx context.Context, rawURL string) error {
+	if len(rawURL) > maxURLLength {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "url longer than %d characters", maxURLLength)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return util.WrapErrorf(err, util.ErrCodeInvalidArgument, "invalid url")
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "url scheme %q is not allowed", u.Scheme)
+	}
+
+	if u.User != nil {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "url must not contain credentials")
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if len(host) == 0 {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "url without host")
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if !util.IsPublicIP(ip) {
+			return util.NewErrorf(util.ErrCodeInvalidArgument, "url points to a private address")
+		}
+	} else if !strings.Contains(host, ".") || host == "localhost" || hasSuffix(host, "localhost", "local", "internal") {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "url points to a private host")
+	}
+
+	if hasSuffix(host, s.blocked...) {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "url host %q is blocked", host)
+	}
+
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+
+	return s.checkAddresses(ctx, host)
+}
+
+func (s *LocalScanner) checkAddresses(ctx context.Context, host string) error {
+	addrs, err := s.resolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return util.WrapErrorf(err, util.ErrCodeInvalidArgument, "url host %q could not be resolved", host)
+	}
+
+	for _, addr := range addrs {
+		if !util.IsPublicIP(addr.IP) {
+			return util.NewErrorf(util.ErrCodeInvalidArgument, "url host %q points to a private address", host)
+		}
+	}
+
+	return nil
+}
+
+// hasSuffix reports whether host is one of domains or a subdomain of one.
+func hasSuffix(host string, domains ...string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
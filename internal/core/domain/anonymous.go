@@ -0,0 +1,9 @@
+package domain
+
+// AnonymousClient identifies the unauthenticated caller creating a link.
+type AnonymousClient struct {
+	IP string
+	// Verification is the CAPTCHA response or proof of work sent along
+	// with the request.
+	Verification string
+}
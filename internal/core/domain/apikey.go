@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// APIKey is a long-lived credential users create for machine clients. Only
+// a hash of the secret is stored, while the ID is also embedded in the key
+// so it can be looked up.
+type APIKey struct {
+	ID           string     `json:"id"`
+	UserID       string     `json:"user_id"`
+	Name         string     `json:"name"`
+	Hash         string     `json:"-"`
+	Scopes       []string   `json:"scopes,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	CreationTime time.Time  `json:"creation_time"`
+}
+
+// Active reports whether the key can still be used at the given time.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Audited actions on links.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// AuditEvent records a change made to a link. Before and After hold the
+// link as it was and as it became, so creations have no Before and
+// deletions no After.
+type AuditEvent struct {
+	ID       string           `json:"id"`
+	Hash     string           `json:"hash"`
+	Action   string           `json:"action"`
+	Actor    string           `json:"actor,omitempty"`
+	APIKeyID string           `json:"api_key_id,omitempty"`
+	Before   *Link            `json:"before,omitempty"`
+	After    *Link            `json:"after,omitempty"`
+	Request  *RequestMetadata `json:"request,omitempty"`
+	Time     time.Time        `json:"time"`
+}
+
+// AuditFilter selects audit events. Empty fields match every event and
+// results are returned most recent first, up to Limit events.
+type AuditFilter struct {
+	Hash   string
+	Actor  string
+	Action string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// Matches reports whether the event satisfies the filter.
+func (f *AuditFilter) Matches(event *AuditEvent) bool {
+	if len(f.Hash) > 0 && event.Hash != f.Hash {
+		return false
+	}
+
+	if len(f.Actor) > 0 && event.Actor != f.Actor {
+		return false
+	}
+
+	if len(f.Action) > 0 && event.Action != f.Action {
+		return false
+	}
+
+	if !f.Since.IsZero() && event.Time.Before(f.Since) {
+		return false
+	}
+
+	return f.Until.IsZero() || event.Time.Before(f.Until)
+}
+
+// RequestMetadata describes the API request that caused a change.
+type RequestMetadata struct {
+	RequestID string `json:"request_id,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+type requestMetadataKey struct{}
+
+// ContextWithRequestMetadata returns a copy of ctx carrying the metadata of
+// the request being served.
+func ContextWithRequestMetadata(ctx context.Context, metadata *RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestMetadataKey{}, metadata)
+}
+
+// RequestMetadataFromContext returns the metadata stored by
+// ContextWithRequestMetadata.
+func RequestMetadataFromContext(ctx context.Context) (*RequestMetadata, bool) {
+	metadata, ok := ctx.Value(requestMetadataKey{}).(*RequestMetadata)
+	return metadata, ok && metadata != nil
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// LinkInfo is the public summary of a short link shown instead of
+// redirecting when a visitor wants to inspect where it goes.
+type LinkInfo struct {
+	Hash         string     `json:"hash"`
+	OriginalURL  string     `json:"original_url"`
+	Owner        string     `json:"owner"`
+	Clicks       int64      `json:"clicks"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	CreationTime time.Time  `json:"creation_time"`
+}
+
+// LinkDetails is a link as returned by the API, with the number of clicks
+// it received.
+type LinkDetails struct {
+	*Link
+	Clicks int64 `json:"clicks"`
+}
+
+// Public returns a copy of the link without the attributes reserved to
+// those who manage it: its owner, routing settings, tags, notes and
+// version.
+func (l *Link) Public() *Link {
+	return &Link{
+		Hash:         l.Hash,
+		OriginalURL:  l.OriginalURL,
+		Metadata:     l.Metadata,
+		SocialCard:   l.SocialCard,
+		Title:        l.Title,
+		Description:  l.Description,
+		Disabled:     l.Disabled,
+		ExpiresAt:    l.ExpiresAt,
+		CreationTime: l.CreationTime,
+	}
+}
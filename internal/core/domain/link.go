@@ -4,8 +4,36 @@ import "time"
 
 // Link is the structural representation of the application domain
 type Link struct {
-	Hash         string    `json:"hash"`
-	OriginalURL  string    `json:"original_url"`
-	UserID       string    `json:"user_id"`
-	CreationTime time.Time `json:"creation_time"`
+	Hash         string         `json:"hash"`
+	OriginalURL  string         `json:"original_url"`
+	UserID       string         `json:"user_id"`
+	WorkspaceID  string         `json:"workspace_id,omitempty"`
+	UTM          *UTM           `json:"utm,omitempty"`
+	ForwardQuery bool           `json:"forward_query"`
+	Rules        []RedirectRule `json:"rules,omitempty"`
+	Variants     []Variant      `json:"variants,omitempty"`
+	Metadata     *LinkMetadata  `json:"metadata,omitempty"`
+	SocialCard   *SocialCard    `json:"social_card,omitempty"`
+	Title        string         `json:"title,omitempty"`
+	Description  string         `json:"description,omitempty"`
+	Tags         []string       `json:"tags,omitempty"`
+	Notes        string         `json:"notes,omitempty"`
+	Disabled     bool           `json:"disabled"`
+	Anonymous    bool           `json:"anonymous,omitempty"`
+	ExpiresAt    *time.Time     `json:"expires_at,omitempty"`
+	DeletedAt    *time.Time     `json:"deleted_at,omitempty"`
+	CreationTime time.Time      `json:"creation_time"`
+	// Version is incremented by every change, so concurrent editors can
+	// detect that the link changed since they read it.
+	Version int64 `json:"version"`
+}
+
+// Expired reports whether the link stopped redirecting before now.
+func (l *Link) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// Deleted reports whether the link was moved to the trash.
+func (l *Link) Deleted() bool {
+	return l.DeletedAt != nil
 }
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// LinkMetadata is the preview information extracted from the destination page.
+type LinkMetadata struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Favicon     string    `json:"favicon,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
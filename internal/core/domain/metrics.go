@@ -14,5 +14,6 @@ type LinkMetrics struct {
 	UserAgentName  string    `json:"user_agent_name"`
 	Version        string    `json:"version"`
 	AcceptLanguage string    `json:"accept_language"`
+	Variant        string    `json:"variant,omitempty"`
 	AccessTime     time.Time `json:"access_time"`
 }
@@ -0,0 +1,51 @@
+package domain
+
+import "context"
+
+// Principal is the authenticated caller of the API with the authorizations
+// granted to it by the identity provider.
+type Principal struct {
+	UserID   string   `json:"user_id"`
+	Username string   `json:"username,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+	// APIKeyID is set when the caller authenticated with an API key.
+	APIKeyID string `json:"api_key_id,omitempty"`
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying the authenticated
+// principal, so the request is only authenticated once.
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored by ContextWithPrincipal.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+func (p *Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return contains(p.Scopes, scope)
+}
+
+func (p *Principal) InGroup(group string) bool {
+	return contains(p.Groups, group)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
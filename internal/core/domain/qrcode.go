@@ -0,0 +1,21 @@
+package domain
+
+import "image/color"
+
+// QR code error correction levels, from least to most tolerant of damage.
+const (
+	QRCodeLevelLow      = "L"
+	QRCodeLevelMedium   = "M"
+	QRCodeLevelQuartile = "Q"
+	QRCodeLevelHigh     = "H"
+)
+
+// QRCodeOptions describes how a QR code is rendered. Size is the maximum
+// width in pixels and Margin the quiet zone in modules.
+type QRCodeOptions struct {
+	Size       int
+	Level      string
+	Margin     int
+	Foreground color.RGBA
+	Background color.RGBA
+}
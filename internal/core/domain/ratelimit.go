@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// RateLimit allows Limit requests per Period, of which up to Burst may be
+// made at once. Burst defaults to Limit.
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// RateLimitDecision is the outcome of counting a request against a limit.
+type RateLimitDecision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the full burst is available again.
+	ResetAfter time.Duration
+	// RetryAfter is the time until the next request is allowed, zero when
+	// the request was allowed.
+	RetryAfter time.Duration
+}
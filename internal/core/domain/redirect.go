@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"net/url"
+	"time"
+)
+
+// Visit describes the incoming request to a short link.
+type Visit struct {
+	Query     url.Values
+	Suffix    string
+	OS        string
+	Device    string
+	Country   string
+	Languages []string
+	Time      time.Time
+	// Variant is the variant previously assigned to the visitor, if any.
+	Variant string
+}
+
+// Redirect is the outcome of resolving a short link for a visit.
+type Redirect struct {
+	Link    *Link
+	URL     string
+	Variant string
+}
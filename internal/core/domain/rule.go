@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Device classes a redirect rule can target.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+// RedirectRule sends visitors matching all of its conditions to Target.
+// Rules are evaluated in order and the link's original URL is used when
+// none of them match.
+type RedirectRule struct {
+	Conditions RuleConditions `json:"conditions"`
+	Target     string         `json:"target"`
+}
+
+// RuleConditions restricts the visitors a rule applies to. Empty lists
+// match every visitor, and values within a list are alternatives.
+type RuleConditions struct {
+	OS        []string   `json:"os,omitempty"`
+	Device    []string   `json:"device,omitempty"`
+	Country   []string   `json:"country,omitempty"`
+	Language  []string   `json:"language,omitempty"`
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+}
+
+// Matches reports whether the visit satisfies every condition of the rule.
+func (r *RedirectRule) Matches(visit *Visit) bool {
+	c := r.Conditions
+
+	if c.NotBefore != nil && visit.Time.Before(*c.NotBefore) {
+		return false
+	}
+
+	if c.NotAfter != nil && visit.Time.After(*c.NotAfter) {
+		return false
+	}
+
+	return matchAny(c.OS, visit.OS) &&
+		matchAny(c.Device, visit.Device) &&
+		matchAny(c.Country, visit.Country) &&
+		matchLanguage(c.Language, visit.Languages)
+}
+
+func matchAny(allowed []string, value string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, a := range allowed {
+		if strings.EqualFold(a, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchLanguage accepts both exact tags ("pt-BR") and primary subtags ("pt").
+func matchLanguage(allowed []string, languages []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, lang := range languages {
+		primary := strings.SplitN(lang, "-", 2)[0]
+		for _, a := range allowed {
+			if strings.EqualFold(a, lang) || strings.EqualFold(a, primary) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// LinkQuery searches the personal links of a user, or the links of a
+// workspace, most recently created first. Every tag must be present,
+// Domain matches the destination host and its subdomains, and Title is a
+// case-insensitive substring of the title.
+type LinkQuery struct {
+	UserID      string
+	WorkspaceID string
+	Tags        []string
+	Domain      string
+	Title       string
+	Since       time.Time
+	Until       time.Time
+	Limit       int
+}
+
+// Matches reports whether the link satisfies the criteria of the query,
+// besides its owner.
+func (q *LinkQuery) Matches(link *Link) bool {
+	for _, tag := range q.Tags {
+		if !contains(link.Tags, tag) {
+			return false
+		}
+	}
+
+	if len(q.Domain) > 0 {
+		host := LinkDomain(link.OriginalURL)
+		if host != q.Domain && !strings.HasSuffix(host, "."+q.Domain) {
+			return false
+		}
+	}
+
+	if len(q.Title) > 0 && !strings.Contains(strings.ToLower(link.Title), strings.ToLower(q.Title)) {
+		return false
+	}
+
+	if !q.Since.IsZero() && link.CreationTime.Before(q.Since) {
+		return false
+	}
+
+	return q.Until.IsZero() || link.CreationTime.Before(q.Until)
+}
+
+// Owner identifies the owner whose links are searched, in the format of
+// LinkOwner.
+func (q *LinkQuery) Owner() string {
+	return owner(q.UserID, q.WorkspaceID)
+}
+
+// LinkOwner identifies who a link belongs to: its workspace, or its
+// creator for personal links.
+func LinkOwner(link *Link) string {
+	return owner(link.UserID, link.WorkspaceID)
+}
+
+func owner(userID string, workspaceID string) string {
+	if len(workspaceID) > 0 {
+		return "workspace:" + workspaceID
+	}
+
+	return "user:" + userID
+}
+
+// LinkDomain returns the lowercase host of the destination url.
+func LinkDomain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
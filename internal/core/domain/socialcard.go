@@ -0,0 +1,14 @@
+package domain
+
+// SocialCard overrides the Open Graph and Twitter Card tags that link
+// preview crawlers see for a short link.
+type SocialCard struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// IsZero reports whether the card overrides nothing.
+func (c *SocialCard) IsZero() bool {
+	return c == nil || *c == SocialCard{}
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "net/url"
+
+// UTM holds the campaign parameters merged into the destination URL on redirect.
+type UTM struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// Values returns the non-empty UTM fields keyed by their query parameter name.
+func (u *UTM) Values() url.Values {
+	values := url.Values{}
+	if u == nil {
+		return values
+	}
+
+	for key, value := range map[string]string{
+		"utm_source":   u.Source,
+		"utm_medium":   u.Medium,
+		"utm_campaign": u.Campaign,
+		"utm_term":     u.Term,
+		"utm_content":  u.Content,
+	} {
+		if len(value) > 0 {
+			values.Set(key, value)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,9 @@
+package domain
+
+// Variant is one of the weighted destinations a link splits its traffic
+// across, used for A/B tests and rotations.
+type Variant struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// Workspace roles, from most to least privileged. Owners manage members,
+// editors manage the workspace links and viewers can only read them.
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// Workspace groups links owned collectively by its members.
+type Workspace struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	CreatedBy    string    `json:"created_by"`
+	CreationTime time.Time `json:"creation_time"`
+	// Role is the role of the user the workspace was listed for.
+	Role string `json:"role,omitempty"`
+}
+
+// Member is the membership of a user in a workspace.
+type Member struct {
+	WorkspaceID string    `json:"workspace_id"`
+	UserID      string    `json:"user_id"`
+	Role        string    `json:"role"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// ValidRole reports whether role is one of the workspace roles.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAllows reports whether role grants at least the privileges of required.
+func RoleAllows(role string, required string) bool {
+	return roleRanks[role] >= roleRanks[required] && roleRanks[role] > 0
+}
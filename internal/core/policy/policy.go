@@ -0,0 +1,45 @@
+package policy
+
+import (
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// Policy decides which operations a principal may perform based on the
+// scopes and roles of its token. Empty requirements are not enforced.
+type Policy struct {
+	readScope  string
+	writeScope string
+	adminRole  string
+}
+
+func New(readScope string, writeScope string, adminRole string) *Policy {
+	return &Policy{
+		readScope:  readScope,
+		writeScope: writeScope,
+		adminRole:  adminRole,
+	}
+}
+
+// CanRead checks that the principal may read the links it has access to.
+func (p *Policy) CanRead(principal *domain.Principal) error {
+	return p.requireScope(principal, p.readScope)
+}
+
+// CanWrite checks that the principal may create and modify links.
+func (p *Policy) CanWrite(principal *domain.Principal) error {
+	return p.requireScope(principal, p.writeScope)
+}
+
+// IsAdmin reports whether the principal can manage any user's links.
+func (p *Policy) IsAdmin(principal *domain.Principal) bool {
+	return len(p.adminRole) > 0 && principal.HasRole(p.adminRole)
+}
+
+func (p *Policy) requireScope(principal *domain.Principal, scope string) error {
+	if len(scope) == 0 || principal.HasScope(scope) || p.IsAdmin(principal) {
+		return nil
+	}
+
+	return util.NewErrorf(util.ErrCodeForbidden, "token is missing the %s scope", scope)
+}
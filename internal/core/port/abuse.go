@@ -0,0 +1,24 @@
+package port
+
+import (
+	"context"
+	"time"
+)
+
+// URLScanner rejects destinations that must not be shortened.
+type URLScanner interface {
+	Scan(ctx context.Context, rawURL string) error
+}
+
+// HumanVerifier checks that a request was made by a person, from a CAPTCHA
+// response or a proof of work bound to resource.
+type HumanVerifier interface {
+	Verify(ctx context.Context, token string, remoteIP string, resource string) error
+}
+
+// ReplayGuard remembers single-use tokens, such as proofs of work, so each
+// is accepted once by every instance of the service.
+type ReplayGuard interface {
+	// Claim records the token for ttl and reports whether it was unused.
+	Claim(ctx context.Context, token string, ttl time.Duration) (bool, error)
+}
@@ -0,0 +1,24 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+)
+
+// APIKeyRepository is an abstraction for storing API keys.
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	FindByID(ctx context.Context, id string) (*domain.APIKey, error)
+	ListByUser(ctx context.Context, userID string) ([]*domain.APIKey, error)
+	Revoke(ctx context.Context, id string, at time.Time) error
+	UpdateLastUsed(ctx context.Context, id string, at time.Time) error
+}
+
+type APIKeyService interface {
+	Create(ctx context.Context, key *domain.APIKey, principal *domain.Principal) (*domain.APIKey, string, error)
+	List(ctx context.Context, principal *domain.Principal) ([]*domain.APIKey, error)
+	Revoke(ctx context.Context, id string, principal *domain.Principal) error
+	Authenticate(ctx context.Context, secret string) (*domain.Principal, error)
+}
@@ -0,0 +1,20 @@
+package port
+
+import (
+	"context"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+)
+
+// AuditRepository is an append-only store of audit events. Events are
+// never updated nor deleted, not even when their link is purged.
+type AuditRepository interface {
+	Append(ctx context.Context, event *domain.AuditEvent) error
+	ListByHash(ctx context.Context, hash string, limit int) ([]*domain.AuditEvent, error)
+	Query(ctx context.Context, filter *domain.AuditFilter) ([]*domain.AuditEvent, error)
+}
+
+type AuditService interface {
+	History(ctx context.Context, hash string, limit int, principal *domain.Principal) ([]*domain.AuditEvent, error)
+	Query(ctx context.Context, filter *domain.AuditFilter, principal *domain.Principal) ([]*domain.AuditEvent, error)
+}
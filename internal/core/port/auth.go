@@ -1,7 +1,11 @@
 package port
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+)
 
 type Auth interface {
-	Authenticate(r *http.Request, w http.ResponseWriter) (string, error)
+	Authenticate(r *http.Request, w http.ResponseWriter) (*domain.Principal, error)
 }
@@ -1,9 +1,13 @@
 package port
 
-import "context"
+import (
+	"context"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+)
 
 type LinkCaching interface {
-	Get(ctx context.Context, hash string) (string, error)
-	Set(ctx context.Context, hash string, originalURL string) error
+	Get(ctx context.Context, hash string) (*domain.Link, error)
+	Set(ctx context.Context, link *domain.Link) error
 	Del(ctx context.Context, hash string) error
 }
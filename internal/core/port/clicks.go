@@ -0,0 +1,9 @@
+package port
+
+import "context"
+
+// ClickCounter keeps the total number of redirects of each link.
+type ClickCounter interface {
+	Incr(ctx context.Context, hash string) error
+	Count(ctx context.Context, hash string) (int64, error)
+}
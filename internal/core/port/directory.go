@@ -0,0 +1,8 @@
+package port
+
+import "context"
+
+// UserDirectory is an abstraction of the identity provider's user store.
+type UserDirectory interface {
+	DisplayName(ctx context.Context, userID string) (string, error)
+}
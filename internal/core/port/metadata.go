@@ -0,0 +1,18 @@
+package port
+
+import (
+	"context"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+)
+
+// MetadataFetcher is an abstraction of a service that extracts preview
+// metadata from a web page.
+type MetadataFetcher interface {
+	Fetch(ctx context.Context, url string) (*domain.LinkMetadata, error)
+}
+
+// PreviewQueue schedules the asynchronous fetching of link previews.
+type PreviewQueue interface {
+	Enqueue(link *domain.Link) bool
+}
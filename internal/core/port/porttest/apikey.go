@@ -0,0 +1,125 @@
+package porttest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// TestAPIKeyRepository checks that keys round-trip through the repository,
+// are listed by user, and record their use and revocation.
+func TestAPIKeyRepository(ctx context.Context, repo port.APIKeyRepository) error {
+	created := now()
+	expires := created.Add(24 * time.Hour)
+	userID := randomUserID()
+
+	keys := []*domain.APIKey{
+		{
+			ID:           randomHex(8),
+			UserID:       userID,
+			Name:         "ci",
+			Hash:         randomHex(32),
+			Scopes:       []string{"links:write", "links:read"},
+			ExpiresAt:    &expires,
+			CreationTime: created,
+		},
+		{
+			ID:           randomHex(8),
+			UserID:       userID,
+			Name:         "reports",
+			Hash:         randomHex(32),
+			CreationTime: created,
+		},
+	}
+
+	for _, key := range keys {
+		if err := repo.Create(ctx, key); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+	}
+
+	got, err := repo.FindByID(ctx, keys[0].ID)
+	if err != nil {
+		return fmt.Errorf("find: %w", err)
+	}
+
+	if err := sameAPIKey(keys[0], got); err != nil {
+		return err
+	}
+
+	if _, err := repo.FindByID(ctx, randomHex(8)); !isCode(err, util.ErrCodeNotFound) {
+		return fmt.Errorf("find missing key returned %v, want not found", err)
+	}
+
+	listed, err := repo.ListByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+
+	if len(listed) != len(keys) {
+		return fmt.Errorf("listed %d keys, want %d", len(listed), len(keys))
+	}
+
+	used := created.Add(time.Minute)
+	if err := repo.UpdateLastUsed(ctx, keys[1].ID, used); err != nil {
+		return fmt.Errorf("update last used: %w", err)
+	}
+	keys[1].LastUsedAt = &used
+
+	revoked := created.Add(2 * time.Minute)
+	if err := repo.Revoke(ctx, keys[1].ID, revoked); err != nil {
+		return fmt.Errorf("revoke: %w", err)
+	}
+	keys[1].RevokedAt = &revoked
+
+	if got, err = repo.FindByID(ctx, keys[1].ID); err != nil {
+		return fmt.Errorf("find revoked key: %w", err)
+	}
+
+	if err := sameAPIKey(keys[1], got); err != nil {
+		return err
+	}
+
+	if got.Active(revoked) {
+		return fmt.Errorf("revoked key %s is still active", got.ID)
+	}
+
+	return nil
+}
+
+func sameAPIKey(want *domain.APIKey, got *domain.APIKey) error {
+	switch {
+	case got.ID != want.ID || got.UserID != want.UserID || got.Name != want.Name || got.Hash != want.Hash:
+		return fmt.Errorf("key = %+v, want %+v", got, want)
+	case !got.CreationTime.Equal(want.CreationTime):
+		return fmt.Errorf("creation time = %v, want %v", got.CreationTime, want.CreationTime)
+	case sortedScopes(got.Scopes) != sortedScopes(want.Scopes):
+		return fmt.Errorf("scopes = %v, want %v", got.Scopes, want.Scopes)
+	}
+
+	for _, err := range []error{
+		sameTime("expires at", want.ExpiresAt, got.ExpiresAt),
+		sameTime("last used at", want.LastUsedAt, got.LastUsedAt),
+		sameTime("revoked at", want.RevokedAt, got.RevokedAt),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sortedScopes ignores the order of scopes, which some backends store as
+// sets.
+func sortedScopes(scopes []string) string {
+	sorted := append([]string(nil), scopes...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, " ")
+}
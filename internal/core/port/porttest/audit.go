@@ -0,0 +1,124 @@
+package porttest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+)
+
+// TestAuditRepository checks that events round-trip through the repository
+// and are listed most recent first, by link and by filter.
+func TestAuditRepository(ctx context.Context, repo port.AuditRepository) error {
+	start := now().Add(-time.Minute)
+	hash := "ct" + randomHex(4)
+	actor := randomUserID()
+
+	before := &domain.Link{Hash: hash, OriginalURL: "https://example.com/before", UserID: actor, CreationTime: start}
+	after := &domain.Link{Hash: hash, OriginalURL: "https://example.com/after", UserID: actor, CreationTime: start}
+
+	events := []*domain.AuditEvent{
+		{
+			ID:      randomUUID(),
+			Hash:    hash,
+			Action:  domain.AuditCreate,
+			Actor:   actor,
+			After:   before,
+			Request: &domain.RequestMetadata{RequestID: randomHex(8), IP: "192.0.2.1", UserAgent: "porttest"},
+			Time:    start,
+		},
+		{
+			ID:       randomUUID(),
+			Hash:     hash,
+			Action:   domain.AuditUpdate,
+			Actor:    actor,
+			APIKeyID: randomHex(6),
+			Before:   before,
+			After:    after,
+			Time:     start.Add(time.Second),
+		},
+		{
+			ID:     randomUUID(),
+			Hash:   hash,
+			Action: domain.AuditPurge,
+			Before: after,
+			Time:   start.Add(2 * time.Second),
+		},
+	}
+
+	for _, event := range events {
+		if err := repo.Append(ctx, event); err != nil {
+			return fmt.Errorf("append: %w", err)
+		}
+	}
+
+	history, err := repo.ListByHash(ctx, hash, 10)
+	if err != nil {
+		return fmt.Errorf("list by hash: %w", err)
+	}
+
+	if len(history) != len(events) {
+		return fmt.Errorf("list by hash returned %d events, want %d", len(history), len(events))
+	}
+
+	for i, event := range history {
+		if err := sameAuditEvent(events[len(events)-1-i], event); err != nil {
+			return fmt.Errorf("list by hash: %w", err)
+		}
+	}
+
+	if limited, err := repo.ListByHash(ctx, hash, 1); err != nil || len(limited) != 1 || limited[0].ID != events[2].ID {
+		return fmt.Errorf("list by hash with limit returned %d events, %v", len(limited), err)
+	}
+
+	queries := []struct {
+		filter domain.AuditFilter
+		want   []*domain.AuditEvent
+	}{
+		{domain.AuditFilter{Hash: hash, Action: domain.AuditUpdate, Limit: 10}, events[1:2]},
+		{domain.AuditFilter{Actor: actor, Since: start.Add(-time.Second), Limit: 10}, []*domain.AuditEvent{events[1], events[0]}},
+		{domain.AuditFilter{Actor: actor, Since: start, Until: start.Add(time.Second), Limit: 10}, events[0:1]},
+	}
+
+	for _, q := range queries {
+		got, err := repo.Query(ctx, &q.filter)
+		if err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+
+		if len(got) != len(q.want) {
+			return fmt.Errorf("query %+v returned %d events, want %d", q.filter, len(got), len(q.want))
+		}
+
+		for i := range got {
+			if got[i].ID != q.want[i].ID {
+				return fmt.Errorf("query %+v returned %s at %d, want %s", q.filter, got[i].ID, i, q.want[i].ID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func sameAuditEvent(want *domain.AuditEvent, got *domain.AuditEvent) error {
+	if want.ID != got.ID || want.Hash != got.Hash || want.Action != got.Action || want.Actor != got.Actor ||
+		want.APIKeyID != got.APIKeyID {
+		return fmt.Errorf("event = %+v, want %+v", got, want)
+	}
+
+	if err := sameTime("time", &want.Time, &got.Time); err != nil {
+		return err
+	}
+
+	if err := sameJSON("before", want.Before, got.Before); err != nil {
+		return err
+	}
+
+	if err := sameJSON("after", want.After, got.After); err != nil {
+		return err
+	}
+
+	return sameJSON("request", want.Request, got.Request)
+}
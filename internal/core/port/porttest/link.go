@@ -0,0 +1,357 @@
+package porttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// TestLinkRepository checks that links round-trip through the repository,
+// including anonymous links without an owner, that updates and deletes
+// are visible to subsequent reads and that stale versions are rejected.
+func TestLinkRepository(ctx context.Context, repo port.LinkRepository) error {
+	created := now()
+	expires := created.Add(time.Hour)
+	notAfter := created.Add(24 * time.Hour)
+
+	link := &domain.Link{
+		Hash:         "ct" + randomHex(4),
+		OriginalURL:  "https://example.com/conformance",
+		UserID:       randomUserID(),
+		WorkspaceID:  randomHex(8),
+		UTM:          &domain.UTM{Source: "newsletter", Campaign: "launch"},
+		ForwardQuery: true,
+		Rules: []domain.RedirectRule{{
+			Conditions: domain.RuleConditions{Device: []string{domain.DeviceMobile}, NotAfter: &notAfter},
+			Target:     "https://m.example.com",
+		}},
+		Variants:     []domain.Variant{{ID: "b", URL: "https://example.com/b", Weight: 1}},
+		SocialCard:   &domain.SocialCard{Title: "Conformance"},
+		Title:        "Conformance",
+		Description:  "Checks the storage adapters",
+		Tags:         []string{"ci", "conformance"},
+		Notes:        "created by the conformance check",
+		ExpiresAt:    &expires,
+		CreationTime: created,
+		Version:      1,
+	}
+
+	if err := repo.Create(ctx, link); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer func() { _ = repo.Delete(context.Background(), link.Hash) }()
+
+	got, err := repo.FindByHash(ctx, link.Hash)
+	if err != nil {
+		return fmt.Errorf("find created link: %w", err)
+	}
+
+	if err := sameLink(link, got); err != nil {
+		return fmt.Errorf("created link: %w", err)
+	}
+
+	if err := checkEach(ctx, repo, link); err != nil {
+		return err
+	}
+
+	link.OriginalURL = "https://example.com/updated"
+	link.UTM = nil
+	link.ForwardQuery = false
+	link.Rules = nil
+	link.Variants = nil
+	link.SocialCard = nil
+	link.Title = "Updated"
+	link.Description = ""
+	link.Tags = []string{"updated"}
+	link.Notes = ""
+	link.Disabled = true
+	if err := repo.Update(ctx, link); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	if err := checkVersions(ctx, repo, link); err != nil {
+		return err
+	}
+
+	metadata := &domain.LinkMetadata{Title: "Updated", FetchedAt: now()}
+	if err := repo.UpdateMetadata(ctx, link, metadata); err != nil {
+		return fmt.Errorf("update metadata: %w", err)
+	}
+	link.Metadata = metadata
+
+	if got, err = repo.FindByHash(ctx, link.Hash); err != nil {
+		return fmt.Errorf("find updated link: %w", err)
+	}
+
+	if err := sameLink(link, got); err != nil {
+		return fmt.Errorf("updated link: %w", err)
+	}
+
+	if link.Version != 2 {
+		return fmt.Errorf("version after metadata update = %d, want 2", link.Version)
+	}
+
+	anonymous := &domain.Link{
+		Hash:         "ct" + randomHex(4),
+		OriginalURL:  "https://example.com/anonymous",
+		Anonymous:    true,
+		ExpiresAt:    &expires,
+		CreationTime: created,
+		Version:      1,
+	}
+
+	if err := repo.Create(ctx, anonymous); err != nil {
+		return fmt.Errorf("create anonymous: %w", err)
+	}
+	defer func() { _ = repo.Delete(context.Background(), anonymous.Hash) }()
+
+	if got, err = repo.FindByHash(ctx, anonymous.Hash); err != nil {
+		return fmt.Errorf("find anonymous link: %w", err)
+	}
+
+	if err := sameLink(anonymous, got); err != nil {
+		return fmt.Errorf("anonymous link: %w", err)
+	}
+
+	if err := checkTrash(ctx, repo, link); err != nil {
+		return err
+	}
+
+	stale := *link
+	stale.Version--
+
+	if err := repo.Purge(ctx, &stale); !isCode(err, util.ErrCodePreconditionFailed) {
+		return fmt.Errorf("purge of a stale version returned %v, want precondition failed", err)
+	}
+
+	if err := repo.Purge(ctx, link); err != nil {
+		return fmt.Errorf("purge: %w", err)
+	}
+
+	if _, err := repo.FindByHash(ctx, link.Hash); !isCode(err, util.ErrCodeNotFound) {
+		return fmt.Errorf("find purged link returned %v, want not found", err)
+	}
+
+	if err := repo.Purge(ctx, link); !isCode(err, util.ErrCodeNotFound) {
+		return fmt.Errorf("purge of a missing link returned %v, want not found", err)
+	}
+
+	if trash, err := repo.ListDeleted(ctx, link.UserID); err != nil || len(trash) != 0 {
+		return fmt.Errorf("trash after purge = %v, %v, want none", hashes(trash), err)
+	}
+
+	return nil
+}
+
+// checkTrash soft deletes the link, expecting it to be listed in the trash
+// of its owner and to be purgeable once deleted, then restores it.
+func checkTrash(ctx context.Context, repo port.LinkRepository, link *domain.Link) error {
+	deletedAt := now()
+	if err := repo.SoftDelete(ctx, link, deletedAt); err != nil {
+		return fmt.Errorf("soft delete: %w", err)
+	}
+	link.DeletedAt = &deletedAt
+
+	got, err := repo.FindByHash(ctx, link.Hash)
+	if err != nil {
+		return fmt.Errorf("find deleted link: %w", err)
+	}
+
+	if err := sameLink(link, got); err != nil {
+		return fmt.Errorf("deleted link: %w", err)
+	}
+
+	trash, err := repo.ListDeleted(ctx, link.UserID)
+	if err != nil {
+		return fmt.Errorf("list deleted: %w", err)
+	}
+
+	if len(trash) != 1 || trash[0].Hash != link.Hash {
+		return fmt.Errorf("trash of %s = %v, want %s", link.UserID, hashes(trash), link.Hash)
+	}
+
+	if err := sameTime("deleted at", &deletedAt, trash[0].DeletedAt); err != nil {
+		return fmt.Errorf("trash: %w", err)
+	}
+
+	for _, before := range []time.Time{deletedAt, deletedAt.Add(time.Millisecond)} {
+		expired, err := repo.ListDeletedBefore(ctx, before)
+		if err != nil {
+			return fmt.Errorf("list deleted before: %w", err)
+		}
+
+		if want := before.After(deletedAt); containsHash(expired, link.Hash) != want {
+			return fmt.Errorf("link deleted at %v listed as deleted before %v: %t, want %t",
+				deletedAt, before, !want, want)
+		}
+	}
+
+	if err := repo.Restore(ctx, link); err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	link.DeletedAt = nil
+
+	if got, err = repo.FindByHash(ctx, link.Hash); err != nil {
+		return fmt.Errorf("find restored link: %w", err)
+	}
+
+	if err := sameLink(link, got); err != nil {
+		return fmt.Errorf("restored link: %w", err)
+	}
+
+	if trash, err = repo.ListDeleted(ctx, link.UserID); err != nil || len(trash) != 0 {
+		return fmt.Errorf("trash after restore = %v, %v, want none", hashes(trash), err)
+	}
+
+	// deleted again, so purging also clears the trash
+	if err := repo.SoftDelete(ctx, link, deletedAt); err != nil {
+		return fmt.Errorf("soft delete: %w", err)
+	}
+
+	return nil
+}
+
+func sameLink(want *domain.Link, got *domain.Link) error {
+	switch {
+	case got.Hash != want.Hash:
+		return fmt.Errorf("hash = %q, want %q", got.Hash, want.Hash)
+	case got.OriginalURL != want.OriginalURL:
+		return fmt.Errorf("original url = %q, want %q", got.OriginalURL, want.OriginalURL)
+	case got.UserID != want.UserID:
+		return fmt.Errorf("user id = %q, want %q", got.UserID, want.UserID)
+	case got.WorkspaceID != want.WorkspaceID:
+		return fmt.Errorf("workspace id = %q, want %q", got.WorkspaceID, want.WorkspaceID)
+	case got.ForwardQuery != want.ForwardQuery:
+		return fmt.Errorf("forward query = %t, want %t", got.ForwardQuery, want.ForwardQuery)
+	case got.Title != want.Title:
+		return fmt.Errorf("title = %q, want %q", got.Title, want.Title)
+	case got.Description != want.Description:
+		return fmt.Errorf("description = %q, want %q", got.Description, want.Description)
+	case got.Notes != want.Notes:
+		return fmt.Errorf("notes = %q, want %q", got.Notes, want.Notes)
+	case got.Disabled != want.Disabled:
+		return fmt.Errorf("disabled = %t, want %t", got.Disabled, want.Disabled)
+	case got.Anonymous != want.Anonymous:
+		return fmt.Errorf("anonymous = %t, want %t", got.Anonymous, want.Anonymous)
+	case !got.CreationTime.Equal(want.CreationTime):
+		return fmt.Errorf("creation time = %v, want %v", got.CreationTime, want.CreationTime)
+	case got.Version != want.Version:
+		return fmt.Errorf("version = %d, want %d", got.Version, want.Version)
+	}
+
+	for _, err := range []error{
+		sameTime("expires at", want.ExpiresAt, got.ExpiresAt),
+		sameTime("deleted at", want.DeletedAt, got.DeletedAt),
+		sameJSON("utm", want.UTM, got.UTM),
+		sameJSON("rules", want.Rules, got.Rules),
+		sameJSON("variants", want.Variants, got.Variants),
+		sameJSON("metadata", want.Metadata, got.Metadata),
+		sameJSON("social card", want.SocialCard, got.SocialCard),
+		sameJSON("tags", want.Tags, got.Tags),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkVersions expects the link to be at version 2 after its first update,
+// and writes made with a stale version or to a missing link to fail.
+func checkVersions(ctx context.Context, repo port.LinkRepository, link *domain.Link) error {
+	if link.Version != 2 {
+		return fmt.Errorf("version after update = %d, want 2", link.Version)
+	}
+
+	stale := *link
+	stale.Version = 1
+	stale.OriginalURL = "https://example.com/stale"
+
+	if err := repo.Update(ctx, &stale); !isCode(err, util.ErrCodePreconditionFailed) {
+		return fmt.Errorf("update of a stale version returned %v, want precondition failed", err)
+	}
+
+	if err := repo.UpdateMetadata(ctx, &stale, &domain.LinkMetadata{Title: "Stale"}); !isCode(err, util.ErrCodePreconditionFailed) {
+		return fmt.Errorf("metadata update of a stale version returned %v, want precondition failed", err)
+	}
+
+	if err := repo.SoftDelete(ctx, &stale, now()); !isCode(err, util.ErrCodePreconditionFailed) {
+		return fmt.Errorf("soft delete of a stale version returned %v, want precondition failed", err)
+	}
+
+	missing := *link
+	missing.Hash = "ct" + randomHex(4)
+
+	if err := repo.Update(ctx, &missing); !isCode(err, util.ErrCodeNotFound) {
+		return fmt.Errorf("update of a missing link returned %v, want not found", err)
+	}
+
+	if err := repo.UpdateMetadata(ctx, &missing, &domain.LinkMetadata{Title: "Missing"}); !isCode(err, util.ErrCodeNotFound) {
+		return fmt.Errorf("metadata update of a missing link returned %v, want not found", err)
+	}
+
+	if _, err := repo.FindByHash(ctx, missing.Hash); !isCode(err, util.ErrCodeNotFound) {
+		return fmt.Errorf("update of a missing link created it: %v", err)
+	}
+
+	return nil
+}
+
+func isCode(err error, code int) bool {
+	var e *util.Error
+	return errors.As(err, &e) && e.Code() == code
+}
+
+func hashes(links []*domain.Link) []string {
+	result := make([]string, 0, len(links))
+	for _, link := range links {
+		result = append(result, link.Hash)
+	}
+
+	return result
+}
+
+func containsHash(links []*domain.Link, hash string) bool {
+	for _, link := range links {
+		if link.Hash == hash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkEach looks for the link among every stored link. Other links may be
+// stored by other checks or runs.
+func checkEach(ctx context.Context, repo port.LinkRepository, link *domain.Link) error {
+	var found *domain.Link
+	if err := repo.Each(ctx, func(l *domain.Link) error {
+		if l.Hash == link.Hash {
+			found = l
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("each: %w", err)
+	}
+
+	if found == nil {
+		return fmt.Errorf("each didn't return link %s", link.Hash)
+	}
+
+	if err := sameLink(link, found); err != nil {
+		return fmt.Errorf("link returned by each: %w", err)
+	}
+
+	errStop := errors.New("stop")
+	if err := repo.Each(ctx, func(*domain.Link) error { return errStop }); !errors.Is(err, errStop) {
+		return fmt.Errorf("each returned %v, want the error of fn", err)
+	}
+
+	return nil
+}
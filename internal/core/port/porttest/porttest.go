@@ -0,0 +1,98 @@
+// Package porttest checks that storage adapters behave as the services
+// expect. Every backend runs the same checks from its tests, against a live
+// database, and they only create records with random identifiers.
+package porttest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// Repositories are the adapters of a storage backend.
+type Repositories struct {
+	Links      port.LinkRepository
+	Workspaces port.WorkspaceRepository
+	APIKeys    port.APIKeyRepository
+	Audit      port.AuditRepository
+	Search     port.LinkIndex
+}
+
+// Run runs the checks of every repository as subtests of t.
+func Run(t *testing.T, repos Repositories) {
+	checks := []struct {
+		name  string
+		check func(ctx context.Context) error
+	}{
+		{"links", func(ctx context.Context) error { return TestLinkRepository(ctx, repos.Links) }},
+		{"workspaces", func(ctx context.Context) error { return TestWorkspaceRepository(ctx, repos.Workspaces) }},
+		{"api keys", func(ctx context.Context) error { return TestAPIKeyRepository(ctx, repos.APIKeys) }},
+		{"audit", func(ctx context.Context) error { return TestAuditRepository(ctx, repos.Audit) }},
+		{"search", func(ctx context.Context) error { return TestLinkIndex(ctx, repos.Links, repos.Search) }},
+	}
+
+	for _, c := range checks {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			if err := c.check(ctx); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// now is truncated to the millisecond precision of every backend.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// randomUserID returns a user id in the format of an identity provider
+// subject, which backends must store as an opaque string.
+func randomUserID() string {
+	return "auth0|" + randomHex(12)
+}
+
+// randomUUID returns a version 4 UUID, the format of event ids.
+func randomUUID() string {
+	id, _ := util.NewUUID()
+	return id
+}
+
+// sameJSON compares values by their JSON encoding, since backends are free
+// to return empty lists as nil.
+func sameJSON(field string, want interface{}, got interface{}) error {
+	w, _ := json.Marshal(want)
+	g, _ := json.Marshal(got)
+	if string(w) != string(g) {
+		return fmt.Errorf("%s = %s, want %s", field, g, w)
+	}
+
+	return nil
+}
+
+func sameTime(field string, want *time.Time, got *time.Time) error {
+	switch {
+	case want == nil && got == nil:
+		return nil
+	case want == nil || got == nil || !want.Equal(*got):
+		return fmt.Errorf("%s = %v, want %v", field, got, want)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package porttest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+)
+
+// TestLinkIndex checks that indexed links are found by each criterion, most
+// recently created first, and only by their owner. The links are also
+// stored in the repository, which some indexes search directly.
+func TestLinkIndex(ctx context.Context, repo port.LinkRepository, index port.LinkIndex) error {
+	start := now().Add(-time.Minute)
+	userID := randomUserID()
+	workspaceID := randomHex(8)
+
+	link := func(url string, title string, tags []string, created time.Time) *domain.Link {
+		return &domain.Link{
+			Hash:         "ct" + randomHex(4),
+			OriginalURL:  url,
+			UserID:       userID,
+			Title:        title,
+			Tags:         tags,
+			CreationTime: created,
+			Version:      1,
+		}
+	}
+
+	report := link("https://example.com/q3", "Quarterly Report", []string{"finance", "report"}, start)
+	roadmap := link("https://docs.example.com/roadmap", "Roadmap", []string{"planning"}, start.Add(time.Second))
+	archive := link("https://archive.example.org/", "Report archive", []string{"report"}, start.Add(2*time.Second))
+	shared := link("https://example.com/shared", "Shared report", []string{"report"}, start.Add(3*time.Second))
+	shared.WorkspaceID = workspaceID
+
+	for _, l := range []*domain.Link{report, roadmap, archive, shared} {
+		if err := repo.Create(ctx, l); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		defer func(l *domain.Link) {
+			_ = index.Remove(context.Background(), l)
+			_ = repo.Delete(context.Background(), l.Hash)
+		}(l)
+
+		if err := index.Index(ctx, l); err != nil {
+			return fmt.Errorf("index: %w", err)
+		}
+	}
+
+	queries := []struct {
+		query domain.LinkQuery
+		want  []*domain.Link
+	}{
+		{domain.LinkQuery{}, []*domain.Link{archive, roadmap, report}},
+		{domain.LinkQuery{Tags: []string{"report"}}, []*domain.Link{archive, report}},
+		{domain.LinkQuery{Tags: []string{"finance", "report"}}, []*domain.Link{report}},
+		{domain.LinkQuery{Domain: "example.com"}, []*domain.Link{roadmap, report}},
+		{domain.LinkQuery{Title: "REPORT"}, []*domain.Link{archive, report}},
+		{domain.LinkQuery{Since: start.Add(time.Second)}, []*domain.Link{archive, roadmap}},
+		{domain.LinkQuery{Until: start.Add(time.Second)}, []*domain.Link{report}},
+		{domain.LinkQuery{Limit: 1}, []*domain.Link{archive}},
+		{domain.LinkQuery{WorkspaceID: workspaceID}, []*domain.Link{shared}},
+	}
+
+	for _, q := range queries {
+		if err := checkSearch(ctx, index, userID, q.query, q.want); err != nil {
+			return err
+		}
+	}
+
+	roadmap.Tags = []string{"report"}
+	if err := repo.Update(ctx, roadmap); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	if err := index.Index(ctx, roadmap); err != nil {
+		return fmt.Errorf("index after update: %w", err)
+	}
+
+	if err := checkSearch(ctx, index, userID, domain.LinkQuery{Tags: []string{"planning"}}, nil); err != nil {
+		return err
+	}
+
+	if err := checkSearch(ctx, index, userID, domain.LinkQuery{Tags: []string{"report"}}, []*domain.Link{archive, roadmap, report}); err != nil {
+		return err
+	}
+
+	if err := repo.SoftDelete(ctx, archive, now()); err != nil {
+		return fmt.Errorf("soft delete: %w", err)
+	}
+
+	if err := index.Remove(ctx, archive); err != nil {
+		return fmt.Errorf("remove: %w", err)
+	}
+
+	if err := checkSearch(ctx, index, userID, domain.LinkQuery{Tags: []string{"report"}}, []*domain.Link{roadmap, report}); err != nil {
+		return err
+	}
+
+	// deleted without being removed from the index, as when an update of
+	// the index fails, and cleared when the owner's links are reindexed
+	if err := repo.SoftDelete(ctx, report, now()); err != nil {
+		return fmt.Errorf("soft delete: %w", err)
+	}
+
+	if err := index.Clear(ctx, domain.LinkOwner(roadmap)); err != nil {
+		return fmt.Errorf("clear: %w", err)
+	}
+
+	if err := index.Index(ctx, roadmap); err != nil {
+		return fmt.Errorf("index after clear: %w", err)
+	}
+
+	if err := checkSearch(ctx, index, userID, domain.LinkQuery{}, []*domain.Link{roadmap}); err != nil {
+		return err
+	}
+
+	return checkSearch(ctx, index, userID, domain.LinkQuery{WorkspaceID: workspaceID}, []*domain.Link{shared})
+}
+
+func checkSearch(ctx context.Context, index port.LinkIndex, userID string, query domain.LinkQuery, want []*domain.Link) error {
+	query.UserID = userID
+	if query.Limit == 0 {
+		query.Limit = 10
+	}
+
+	got, err := index.Search(ctx, &query)
+	if err != nil {
+		return fmt.Errorf("search %+v: %w", query, err)
+	}
+
+	if err := sameJSON(fmt.Sprintf("search %+v", query), hashes(want), got); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package porttest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// TestWorkspaceRepository checks that workspaces are created with their
+// owner and that memberships are listed both by workspace and by user.
+func TestWorkspaceRepository(ctx context.Context, repo port.WorkspaceRepository) error {
+	created := now()
+	owner := randomUserID()
+	editor := randomUserID()
+
+	workspace := &domain.Workspace{
+		ID:           randomHex(8),
+		Name:         "Conformance",
+		CreatedBy:    owner,
+		CreationTime: created,
+	}
+
+	if err := repo.Create(ctx, workspace, &domain.Member{
+		WorkspaceID: workspace.ID,
+		UserID:      owner,
+		Role:        domain.RoleOwner,
+		JoinedAt:    created,
+	}); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer func() {
+		_ = repo.DeleteMember(context.Background(), workspace.ID, editor)
+		_ = repo.DeleteMember(context.Background(), workspace.ID, owner)
+	}()
+
+	got, err := repo.FindByID(ctx, workspace.ID)
+	if err != nil {
+		return fmt.Errorf("find: %w", err)
+	}
+
+	if got.ID != workspace.ID || got.Name != workspace.Name || got.CreatedBy != workspace.CreatedBy ||
+		!got.CreationTime.Equal(workspace.CreationTime) {
+		return fmt.Errorf("workspace = %+v, want %+v", got, workspace)
+	}
+
+	if _, err := repo.FindByID(ctx, randomHex(8)); !isCode(err, util.ErrCodeNotFound) {
+		return fmt.Errorf("find missing workspace returned %v, want not found", err)
+	}
+
+	if err := repo.SaveMember(ctx, &domain.Member{
+		WorkspaceID: workspace.ID,
+		UserID:      editor,
+		Role:        domain.RoleViewer,
+		JoinedAt:    created,
+	}); err != nil {
+		return fmt.Errorf("save member: %w", err)
+	}
+
+	// saving an existing member changes its role
+	if err := repo.SaveMember(ctx, &domain.Member{
+		WorkspaceID: workspace.ID,
+		UserID:      editor,
+		Role:        domain.RoleEditor,
+		JoinedAt:    created,
+	}); err != nil {
+		return fmt.Errorf("save existing member: %w", err)
+	}
+
+	member, err := repo.FindMember(ctx, workspace.ID, editor)
+	if err != nil {
+		return fmt.Errorf("find member: %w", err)
+	}
+
+	if member.WorkspaceID != workspace.ID || member.UserID != editor || member.Role != domain.RoleEditor ||
+		!member.JoinedAt.Equal(created) {
+		return fmt.Errorf("member = %+v, want editor %s of %s", member, editor, workspace.ID)
+	}
+
+	members, err := repo.ListMembers(ctx, workspace.ID)
+	if err != nil {
+		return fmt.Errorf("list members: %w", err)
+	}
+
+	if len(members) != 2 {
+		return fmt.Errorf("listed %d members, want 2", len(members))
+	}
+
+	memberships, err := repo.ListByUser(ctx, editor)
+	if err != nil {
+		return fmt.Errorf("list by user: %w", err)
+	}
+
+	if len(memberships) != 1 || memberships[0].WorkspaceID != workspace.ID || memberships[0].Role != domain.RoleEditor {
+		return fmt.Errorf("memberships of %s = %+v, want editor of %s", editor, memberships, workspace.ID)
+	}
+
+	if err := repo.DeleteMember(ctx, workspace.ID, editor); err != nil {
+		return fmt.Errorf("delete member: %w", err)
+	}
+
+	if _, err := repo.FindMember(ctx, workspace.ID, editor); !isCode(err, util.ErrCodeNotFound) {
+		return fmt.Errorf("find deleted member returned %v, want not found", err)
+	}
+
+	if memberships, err = repo.ListByUser(ctx, editor); err != nil || len(memberships) != 0 {
+		return fmt.Errorf("memberships of deleted member = %+v, %v, want none", memberships, err)
+	}
+
+	return nil
+}
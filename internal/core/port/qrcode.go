@@ -0,0 +1,10 @@
+package port
+
+import "github.com/hugosrc/shortlink/internal/core/domain"
+
+// QRCodeGenerator is an abstraction of a service responsible for rendering
+// content as QR code images.
+type QRCodeGenerator interface {
+	PNG(content string, opts *domain.QRCodeOptions) ([]byte, error)
+	SVG(content string, opts *domain.QRCodeOptions) ([]byte, error)
+}
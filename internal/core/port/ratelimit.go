@@ -0,0 +1,12 @@
+package port
+
+import (
+	"context"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+)
+
+// RateLimiter counts requests made under key against limit.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit domain.RateLimit) (*domain.RateLimitDecision, error)
+}
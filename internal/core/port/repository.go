@@ -2,14 +2,32 @@ package port
 
 import (
 	"context"
+	"time"
 
 	"github.com/hugosrc/shortlink/internal/core/domain"
 )
 
 // LinkRepository is an abstraction for accessing a data storage system.
+// Deleted links are kept, and still found by hash, until they are purged
+// with Delete or Purge.
+//
+// Update, UpdateMetadata, SoftDelete, Restore and Purge only apply while
+// the stored version is still the version of the given link, failing with
+// a precondition error otherwise. Update, SoftDelete and Restore increment
+// the version of the link on success; UpdateMetadata leaves it as is, since
+// preview metadata isn't a change made by users.
 type LinkRepository interface {
 	Create(ctx context.Context, link *domain.Link) error
 	FindByHash(ctx context.Context, hash string) (*domain.Link, error)
 	Delete(ctx context.Context, hash string) error
-	Update(ctx context.Context, hash string, newURL string) error
+	Update(ctx context.Context, link *domain.Link) error
+	UpdateMetadata(ctx context.Context, link *domain.Link, metadata *domain.LinkMetadata) error
+	SoftDelete(ctx context.Context, link *domain.Link, at time.Time) error
+	Restore(ctx context.Context, link *domain.Link) error
+	Purge(ctx context.Context, link *domain.Link) error
+	ListDeleted(ctx context.Context, userID string) ([]*domain.Link, error)
+	ListDeletedBefore(ctx context.Context, before time.Time) ([]*domain.Link, error)
+	// Each calls fn with every stored link, including deleted ones, until
+	// fn fails. It is meant for maintenance, such as rebuilding indexes.
+	Each(ctx context.Context, fn func(link *domain.Link) error) error
 }
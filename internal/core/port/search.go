@@ -0,0 +1,21 @@
+package port
+
+import (
+	"context"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+)
+
+// LinkIndex finds links by their attributes. The service keeps it up to
+// date after every change, and indexes may lag behind the links, so
+// results are checked against the links themselves.
+type LinkIndex interface {
+	Index(ctx context.Context, link *domain.Link) error
+	Remove(ctx context.Context, link *domain.Link) error
+	// Clear removes every link of the owner, as identified by
+	// domain.LinkOwner, so they can be indexed again from scratch.
+	Clear(ctx context.Context, owner string) error
+	// Search returns the hashes of the matching links, most recently
+	// created first.
+	Search(ctx context.Context, query *domain.LinkQuery) ([]string, error)
+}
@@ -7,8 +7,21 @@ import (
 )
 
 type LinkService interface {
-	Create(ctx context.Context, url string, userID string) (*domain.Link, error)
-	FindByHash(ctx context.Context, hash string) (string, error)
-	Delete(ctx context.Context, hash string, userID string) error
-	Update(ctx context.Context, hash string, newURL string, userID string) (*domain.Link, error)
+	Create(ctx context.Context, link *domain.Link, principal *domain.Principal) (*domain.Link, error)
+	CreateAnonymous(ctx context.Context, link *domain.Link, client *domain.AnonymousClient) (*domain.Link, error)
+	FindByHash(ctx context.Context, hash string) (*domain.Link, error)
+	Resolve(ctx context.Context, hash string, visit *domain.Visit) (*domain.Redirect, error)
+	Inspect(ctx context.Context, hash string) (*domain.LinkInfo, error)
+	Get(ctx context.Context, hash string, principal *domain.Principal) (*domain.LinkDetails, error)
+	RegisterClick(ctx context.Context, hash string) error
+	Delete(ctx context.Context, hash string, version int64, principal *domain.Principal) error
+	Trash(ctx context.Context, principal *domain.Principal) ([]*domain.Link, error)
+	Search(ctx context.Context, query *domain.LinkQuery, principal *domain.Principal) ([]*domain.Link, error)
+	Restore(ctx context.Context, hash string, principal *domain.Principal) (*domain.Link, error)
+	Update(ctx context.Context, link *domain.Link, principal *domain.Principal) (*domain.Link, error)
+	Patch(ctx context.Context, hash string, patch []byte, version int64, principal *domain.Principal) (*domain.Link, error)
+	ListRules(ctx context.Context, hash string, principal *domain.Principal) ([]domain.RedirectRule, error)
+	ReplaceRules(ctx context.Context, hash string, rules []domain.RedirectRule, principal *domain.Principal) (*domain.Link, error)
+	AddRule(ctx context.Context, hash string, rule domain.RedirectRule, principal *domain.Principal) (*domain.Link, error)
+	RemoveRule(ctx context.Context, hash string, index int, principal *domain.Principal) (*domain.Link, error)
 }
@@ -0,0 +1,27 @@
+package port
+
+import (
+	"context"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+)
+
+// WorkspaceRepository is an abstraction for storing workspaces and their members.
+type WorkspaceRepository interface {
+	Create(ctx context.Context, workspace *domain.Workspace, owner *domain.Member) error
+	FindByID(ctx context.Context, id string) (*domain.Workspace, error)
+	FindMember(ctx context.Context, workspaceID string, userID string) (*domain.Member, error)
+	ListMembers(ctx context.Context, workspaceID string) ([]*domain.Member, error)
+	ListByUser(ctx context.Context, userID string) ([]*domain.Member, error)
+	SaveMember(ctx context.Context, member *domain.Member) error
+	DeleteMember(ctx context.Context, workspaceID string, userID string) error
+}
+
+type WorkspaceService interface {
+	Create(ctx context.Context, name string, principal *domain.Principal) (*domain.Workspace, error)
+	Get(ctx context.Context, id string, principal *domain.Principal) (*domain.Workspace, error)
+	List(ctx context.Context, principal *domain.Principal) ([]*domain.Workspace, error)
+	ListMembers(ctx context.Context, id string, principal *domain.Principal) ([]*domain.Member, error)
+	SetMember(ctx context.Context, id string, memberID string, role string, principal *domain.Principal) (*domain.Member, error)
+	RemoveMember(ctx context.Context, id string, memberID string, principal *domain.Principal) error
+}
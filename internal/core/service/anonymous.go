@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// AnonymousGuard holds the abuse controls applied to links created without
+// credentials. The verifier is optional.
+type AnonymousGuard struct {
+	scanner  port.URLScanner
+	limiter  port.RateLimiter
+	verifier port.HumanVerifier
+	limit    domain.RateLimit
+	ttl      time.Duration
+	maxTTL   time.Duration
+}
+
+// NewAnonymousGuard creates the guard. Anonymous links expire after ttl
+// unless the caller asks for an earlier expiration, and never later than
+// maxTTL.
+func NewAnonymousGuard(scanner port.URLScanner, limiter port.RateLimiter, verifier port.HumanVerifier,
+	limit domain.RateLimit, ttl time.Duration, maxTTL time.Duration) *AnonymousGuard {
+	if maxTTL < ttl {
+		maxTTL = ttl
+	}
+
+	return &AnonymousGuard{
+		scanner:  scanner,
+		limiter:  limiter,
+		verifier: verifier,
+		limit:    limit,
+		ttl:      ttl,
+		maxTTL:   maxTTL,
+	}
+}
+
+// admit runs the checks from cheapest to most expensive and sets the
+// expiration of the link.
+func (g *AnonymousGuard) admit(ctx context.Context, link *domain.Link, client *domain.AnonymousClient) error {
+	decision, err := g.limiter.Allow(ctx, "anonymous:"+client.IP, g.limit)
+	if err != nil {
+		return err
+	}
+
+	if !decision.Allowed {
+		return util.NewErrorf(util.ErrCodeTooManyRequests, "too many anonymous links, retry in %s", decision.RetryAfter.Round(time.Second))
+	}
+
+	if g.verifier != nil {
+		if err := g.verifier.Verify(ctx, client.Verification, client.IP, link.OriginalURL); err != nil {
+			return err
+		}
+	}
+
+	if err := g.scanner.Scan(ctx, link.OriginalURL); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if link.ExpiresAt == nil {
+		expiresAt := now.Add(g.ttl)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if !link.ExpiresAt.After(now) {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "expiration time must be in the future")
+	}
+
+	if link.ExpiresAt.After(now.Add(g.maxTTL)) {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "anonymous links expire within %s", g.maxTTL)
+	}
+
+	return nil
+}
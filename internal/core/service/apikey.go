@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const (
+	apiKeyPrefix         = "sl"
+	apiKeyIDBytes        = 6
+	apiKeySecretBytes    = 32
+	maxAPIKeyName        = 100
+	lastUsedUpdateWindow = time.Minute
+)
+
+type APIKeyService struct {
+	repo port.APIKeyRepository
+}
+
+func NewAPIKeyService(repo port.APIKeyRepository) port.APIKeyService {
+	return &APIKeyService{
+		repo: repo,
+	}
+}
+
+// Create issues a new key for the principal and returns it along with the
+// secret, which can't be retrieved again. Keys can only be granted scopes
+// the principal itself holds.
+func (s *APIKeyService) Create(ctx context.Context, key *domain.APIKey, principal *domain.Principal) (*domain.APIKey, string, error) {
+	if err := checkNotAPIKey(principal); err != nil {
+		return nil, "", err
+	}
+
+	key.Name = strings.TrimSpace(key.Name)
+	if len(key.Name) == 0 || len(key.Name) > maxAPIKeyName {
+		return nil, "", util.NewErrorf(util.ErrCodeInvalidArgument, "key name must have between 1 and %d characters", maxAPIKeyName)
+	}
+
+	for _, scope := range key.Scopes {
+		if !principal.HasScope(scope) {
+			return nil, "", util.NewErrorf(util.ErrCodeForbidden, "cannot grant the %s scope", scope)
+		}
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		return nil, "", util.NewErrorf(util.ErrCodeInvalidArgument, "expiration must be in the future")
+	}
+
+	id, err := randomString(apiKeyIDBytes, hex.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := randomString(apiKeySecretBytes, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, "", err
+	}
+
+	plain := apiKeyPrefix + "_" + id + "_" + secret
+
+	key.ID = id
+	key.UserID = principal.UserID
+	key.Hash = hashAPIKey(plain)
+	key.LastUsedAt = nil
+	key.RevokedAt = nil
+	key.CreationTime = now
+
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return key, plain, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, principal *domain.Principal) ([]*domain.APIKey, error) {
+	if err := checkNotAPIKey(principal); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListByUser(ctx, principal.UserID)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, id string, principal *domain.Principal) error {
+	if err := checkNotAPIKey(principal); err != nil {
+		return err
+	}
+
+	key, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if key.UserID != principal.UserID {
+		return util.NewErrorf(util.ErrCodeNotFound, "api key not found")
+	}
+
+	if key.RevokedAt != nil {
+		return nil
+	}
+
+	return s.repo.Revoke(ctx, id, time.Now())
+}
+
+// Authenticate resolves the principal a key acts on behalf of. The key
+// carries its own scopes and never the roles of its owner.
+func (s *APIKeyService) Authenticate(ctx context.Context, plain string) (*domain.Principal, error) {
+	parts := strings.SplitN(plain, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return nil, util.NewErrorf(util.ErrCodeUnauthorized, "malformed api key")
+	}
+
+	key, err := s.repo.FindByID(ctx, parts[1])
+	if err != nil {
+		if isNotFound(err) {
+			return nil, util.NewErrorf(util.ErrCodeUnauthorized, "invalid api key")
+		}
+
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashAPIKey(plain))) != 1 {
+		return nil, util.NewErrorf(util.ErrCodeUnauthorized, "invalid api key")
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, util.NewErrorf(util.ErrCodeUnauthorized, "api key is expired or revoked")
+	}
+
+	// recording every use would turn each request into a write
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedUpdateWindow {
+		go func() {
+			_ = s.repo.UpdateLastUsed(context.Background(), key.ID, now)
+		}()
+	}
+
+	return &domain.Principal{
+		UserID:   key.UserID,
+		Scopes:   key.Scopes,
+		APIKeyID: key.ID,
+	}, nil
+}
+
+// checkNotAPIKey keeps keys from managing keys, otherwise a key about to
+// expire or be revoked could issue itself a replacement that outlives it.
+func checkNotAPIKey(principal *domain.Principal) error {
+	if len(principal.APIKeyID) > 0 {
+		return util.NewErrorf(util.ErrCodeForbidden, "api keys cannot manage api keys")
+	}
+
+	return nil
+}
+
+// hashAPIKey uses a plain SHA-256 since keys have enough entropy not to
+// need a slow password hash.
+func hashAPIKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", util.WrapErrorf(err, util.ErrCodeUnknown, "error generating random bytes")
+	}
+
+	return encode(b), nil
+}
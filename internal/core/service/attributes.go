@@ -0,0 +1,78 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const (
+	maxLinkTitle       = 200
+	maxLinkDescription = 1000
+	maxLinkNotes       = 5000
+	maxLinkTags        = 20
+	maxTagLength       = 50
+)
+
+// validateAttributes checks the descriptive attributes of the link and
+// normalizes its tags.
+func validateAttributes(link *domain.Link) error {
+	link.Title = strings.TrimSpace(link.Title)
+	if len(link.Title) > maxLinkTitle {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "title exceeds %d characters", maxLinkTitle)
+	}
+
+	if len(link.Description) > maxLinkDescription {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "description exceeds %d characters", maxLinkDescription)
+	}
+
+	if len(link.Notes) > maxLinkNotes {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "notes exceed %d characters", maxLinkNotes)
+	}
+
+	tags, err := normalizeTags(link.Tags)
+	if err != nil {
+		return err
+	}
+
+	link.Tags = tags
+
+	return nil
+}
+
+// normalizeTags lowercases and sorts the tags, dropping duplicates, so
+// they compare equal however they were typed.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if len(tag) == 0 || len(tag) > maxTagLength {
+			return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "tags must have between 1 and %d characters", maxTagLength)
+		}
+
+		if strings.ContainsAny(tag, ",\x00") {
+			return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "tag %q contains an invalid character", tag)
+		}
+
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	if len(normalized) > maxLinkTags {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "links can have at most %d tags", maxLinkTags)
+	}
+
+	sort.Strings(normalized)
+
+	return normalized, nil
+}
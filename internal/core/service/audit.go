@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/policy"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+type AuditService struct {
+	audit      port.AuditRepository
+	links      port.LinkRepository
+	workspaces port.WorkspaceRepository
+	policy     *policy.Policy
+}
+
+func NewAuditService(audit port.AuditRepository, links port.LinkRepository, workspaces port.WorkspaceRepository,
+	policy *policy.Policy) port.AuditService {
+	return &AuditService{
+		audit:      audit,
+		links:      links,
+		workspaces: workspaces,
+		policy:     policy,
+	}
+}
+
+// History lists the changes made to a link, most recent first. It is
+// available to whoever can read the link, including while it is in the
+// trash, but only admins see the API keys and requests behind the changes.
+// The history of purged links can only be queried by admins.
+func (s *AuditService) History(ctx context.Context, hash string, limit int, principal *domain.Principal) ([]*domain.AuditEvent, error) {
+	if err := s.policy.CanRead(principal); err != nil {
+		return nil, err
+	}
+
+	link, err := s.links.FindByHash(ctx, hash)
+	if err != nil {
+		if isNotFound(err) && s.policy.IsAdmin(principal) {
+			return s.audit.ListByHash(ctx, hash, auditLimit(limit))
+		}
+
+		return nil, err
+	}
+
+	if err := authorizeLink(ctx, s.policy, s.workspaces, link, principal, domain.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	events, err := s.audit.ListByHash(ctx, hash, auditLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	if s.policy.IsAdmin(principal) {
+		return events, nil
+	}
+
+	redacted := make([]*domain.AuditEvent, 0, len(events))
+	for _, event := range events {
+		copied := *event
+		copied.APIKeyID = ""
+		copied.Request = nil
+		redacted = append(redacted, &copied)
+	}
+
+	return redacted, nil
+}
+
+// Query searches the changes made to every link. It is reserved to admins.
+func (s *AuditService) Query(ctx context.Context, filter *domain.AuditFilter, principal *domain.Principal) ([]*domain.AuditEvent, error) {
+	if !s.policy.IsAdmin(principal) {
+		return nil, util.NewErrorf(util.ErrCodeForbidden, "user does not have permission")
+	}
+
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "since must be before until")
+	}
+
+	query := *filter
+	query.Limit = auditLimit(filter.Limit)
+
+	return s.audit.Query(ctx, &query)
+}
+
+func auditLimit(limit int) int {
+	if limit <= 0 {
+		return defaultAuditLimit
+	}
+
+	if limit > maxAuditLimit {
+		return maxAuditLimit
+	}
+
+	return limit
+}
+
+// recordAudit appends the change of a link to the audit trail, attributing
+// it to the principal and the request found in ctx. Changes made by the
+// service itself have no principal. The change has already been stored, so
+// failing to record it doesn't fail the change; the repository is expected
+// to report its own failures.
+func recordAudit(ctx context.Context, audit port.AuditRepository, action string, before *domain.Link, after *domain.Link,
+	principal *domain.Principal) {
+	id, err := util.NewUUID()
+	if err != nil {
+		return
+	}
+
+	event := &domain.AuditEvent{
+		ID:     id,
+		Action: action,
+		Before: before,
+		After:  after,
+		Time:   time.Now().UTC(),
+	}
+
+	if after != nil {
+		event.Hash = after.Hash
+	} else if before != nil {
+		event.Hash = before.Hash
+	}
+
+	if principal != nil {
+		event.Actor = principal.UserID
+		event.APIKeyID = principal.APIKeyID
+	}
+
+	if metadata, ok := domain.RequestMetadataFromContext(ctx); ok {
+		event.Request = metadata
+	}
+
+	_ = audit.Append(ctx, event)
+}
+
+// snapshot deep copies the link, so later changes don't alter the state
+// recorded in the audit trail.
+func snapshot(link *domain.Link) (*domain.Link, error) {
+	data, err := json.Marshal(link)
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "json marshal error")
+	}
+
+	var copied domain.Link
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "json unmarshal error")
+	}
+
+	return &copied, nil
+}
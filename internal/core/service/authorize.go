@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/policy"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// authorize checks that the principal holds at least the required role on
+// the link. Personal links are only accessible to their creator, links owned
+// by a workspace follow the user's membership role and admins can manage
+// every link.
+func (s *LinkService) authorize(ctx context.Context, link *domain.Link, principal *domain.Principal, required string) error {
+	return authorizeLink(ctx, s.policy, s.workspaces, link, principal, required)
+}
+
+func authorizeLink(ctx context.Context, policy *policy.Policy, workspaces port.WorkspaceRepository, link *domain.Link,
+	principal *domain.Principal, required string) error {
+	if policy.IsAdmin(principal) {
+		return nil
+	}
+
+	if len(link.WorkspaceID) == 0 {
+		if link.UserID != principal.UserID {
+			return util.NewErrorf(util.ErrCodeForbidden, "user does not have permission")
+		}
+
+		return nil
+	}
+
+	return authorizeWorkspace(ctx, workspaces, link.WorkspaceID, principal.UserID, required)
+}
+
+func authorizeWorkspace(ctx context.Context, workspaces port.WorkspaceRepository, workspaceID string, userID string, required string) error {
+	member, err := workspaces.FindMember(ctx, workspaceID, userID)
+	if err != nil {
+		if isNotFound(err) {
+			return util.NewErrorf(util.ErrCodeForbidden, "user is not a member of the workspace")
+		}
+
+		return err
+	}
+
+	if !domain.RoleAllows(member.Role, required) {
+		return util.NewErrorf(util.ErrCodeForbidden, "user does not have permission")
+	}
+
+	return nil
+}
+
+func isNotFound(err error) bool {
+	return hasCode(err, util.ErrCodeNotFound)
+}
+
+func isPreconditionFailed(err error) bool {
+	return hasCode(err, util.ErrCodePreconditionFailed)
+}
+
+func hasCode(err error, code int) bool {
+	var appError *util.Error
+	return errors.As(err, &appError) && appError.Code() == code
+}
@@ -2,107 +2,459 @@ package service
 
 import (
 	"context"
+	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/policy"
 	"github.com/hugosrc/shortlink/internal/core/port"
 	"github.com/hugosrc/shortlink/internal/util"
 )
 
+// maxUpdateAttempts bounds the retries of updates made without an expected
+// version that lose the race against a concurrent change.
+const maxUpdateAttempts = 3
+
 type LinkService struct {
-	counter port.Counter
-	encoder port.Encoder
-	caching port.LinkCaching
-	repo    port.LinkRepository
+	counter    port.Counter
+	encoder    port.Encoder
+	caching    port.LinkCaching
+	repo       port.LinkRepository
+	previews   port.PreviewQueue
+	clicks     port.ClickCounter
+	directory  port.UserDirectory
+	workspaces port.WorkspaceRepository
+	policy     *policy.Policy
+	anonymous  *AnonymousGuard
+	retention  time.Duration
+	audit      port.AuditRepository
+	index      port.LinkIndex
 }
 
-func NewLinkService(counter port.Counter, encoder port.Encoder, caching port.LinkCaching, repo port.LinkRepository) port.LinkService {
+func NewLinkService(counter port.Counter, encoder port.Encoder, caching port.LinkCaching, repo port.LinkRepository,
+	previews port.PreviewQueue, clicks port.ClickCounter, directory port.UserDirectory,
+	workspaces port.WorkspaceRepository, policy *policy.Policy, anonymous *AnonymousGuard,
+	retention time.Duration, audit port.AuditRepository, index port.LinkIndex) port.LinkService {
+	if retention <= 0 {
+		retention = defaultTrashRetention
+	}
+
 	return &LinkService{
-		counter: counter,
-		encoder: encoder,
-		caching: caching,
-		repo:    repo,
+		counter:    counter,
+		encoder:    encoder,
+		caching:    caching,
+		repo:       repo,
+		previews:   previews,
+		clicks:     clicks,
+		directory:  directory,
+		workspaces: workspaces,
+		policy:     policy,
+		anonymous:  anonymous,
+		retention:  retention,
+		audit:      audit,
+		index:      index,
+	}
+}
+
+func (s *LinkService) Create(ctx context.Context, link *domain.Link, principal *domain.Principal) (*domain.Link, error) {
+	if err := s.policy.CanWrite(principal); err != nil {
+		return nil, err
+	}
+
+	link.UserID = principal.UserID
+
+	if err := validateURL(link.OriginalURL); err != nil {
+		return nil, err
+	}
+
+	if err := validateRules(link.Rules); err != nil {
+		return nil, err
+	}
+
+	if err := validateVariants(link.Variants); err != nil {
+		return nil, err
+	}
+
+	if err := validateSocialCard(link.SocialCard); err != nil {
+		return nil, err
+	}
+
+	if err := validateAttributes(link); err != nil {
+		return nil, err
+	}
+
+	if link.SocialCard.IsZero() {
+		link.SocialCard = nil
+	}
+
+	if link.ExpiresAt != nil && !link.ExpiresAt.After(time.Now()) {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "expiration time must be in the future")
+	}
+
+	if len(link.WorkspaceID) > 0 && !s.policy.IsAdmin(principal) {
+		if err := authorizeWorkspace(ctx, s.workspaces, link.WorkspaceID, principal.UserID, domain.RoleEditor); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.store(ctx, link, principal)
+}
+
+// CreateAnonymous shortens a link for a caller without credentials. Only
+// the destination, UTM parameters and expiration are kept, and the link is
+// admitted by the abuse controls first.
+func (s *LinkService) CreateAnonymous(ctx context.Context, link *domain.Link, client *domain.AnonymousClient) (*domain.Link, error) {
+	if s.anonymous == nil {
+		return nil, util.NewErrorf(util.ErrCodeUnauthorized, "anonymous links are disabled")
+	}
+
+	if err := validateURL(link.OriginalURL); err != nil {
+		return nil, err
 	}
+
+	anonymous := &domain.Link{
+		OriginalURL: link.OriginalURL,
+		UTM:         link.UTM,
+		Anonymous:   true,
+		ExpiresAt:   link.ExpiresAt,
+	}
+
+	if err := s.anonymous.admit(ctx, anonymous, client); err != nil {
+		return nil, err
+	}
+
+	return s.store(ctx, anonymous, nil)
 }
 
-func (s *LinkService) Create(ctx context.Context, url string, userID string) (*domain.Link, error) {
+// store assigns the next hash to the link and saves it. Anonymous links
+// are stored without a principal.
+func (s *LinkService) store(ctx context.Context, link *domain.Link, principal *domain.Principal) (*domain.Link, error) {
 	c, err := s.counter.Inc()
 	if err != nil {
 		return nil, err
 	}
 
 	hash := s.encoder.EncodeToString([]byte(strconv.Itoa(c)))
-	link := &domain.Link{
-		Hash:         hash[0:7],
-		OriginalURL:  url,
-		UserID:       userID,
-		CreationTime: time.Now(),
-	}
+	link.Hash = hash[0:7]
+	link.CreationTime = time.Now()
+	link.Version = 1
 
 	if err := s.repo.Create(ctx, link); err != nil {
 		return nil, err
 	}
 
+	recordAudit(ctx, s.audit, domain.AuditCreate, nil, link, principal)
+
+	s.indexLink(ctx, link)
+
+	_ = s.previews.Enqueue(link)
+
 	return link, nil
 }
 
-func (s *LinkService) FindByHash(ctx context.Context, hash string) (string, error) {
-	url, _ := s.caching.Get(ctx, hash)
+// FindByHash returns the link while it hasn't expired, been disabled nor
+// been deleted. Such links are reported as gone.
+func (s *LinkService) FindByHash(ctx context.Context, hash string) (*domain.Link, error) {
+	link, _ := s.caching.Get(ctx, hash)
+
+	if link == nil {
+		var err error
+		if link, err = s.repo.FindByHash(ctx, hash); err != nil {
+			return nil, err
+		}
 
-	if len(url) > 0 {
-		return url, nil
+		_ = s.caching.Set(ctx, link)
 	}
 
-	link, err := s.repo.FindByHash(ctx, hash)
+	if link.Deleted() {
+		return nil, util.NewErrorf(util.ErrCodeGone, "link has been deleted")
+	}
+
+	if link.Disabled {
+		return nil, util.NewErrorf(util.ErrCodeGone, "link is disabled")
+	}
+
+	if link.Expired(time.Now()) {
+		return nil, util.NewErrorf(util.ErrCodeGone, "link has expired")
+	}
+
+	return link, nil
+}
+
+func (s *LinkService) Resolve(ctx context.Context, hash string, visit *domain.Visit) (*domain.Redirect, error) {
+	link, err := s.FindByHash(ctx, hash)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if len(visit.Suffix) > 0 && !link.ForwardQuery {
+		return nil, util.NewErrorf(util.ErrCodeNotFound, "url not found")
+	}
+
+	redirect := &domain.Redirect{Link: link}
+
+	target := ""
+	for _, rule := range link.Rules {
+		if rule.Matches(visit) {
+			target = rule.Target
+			break
+		}
+	}
+
+	if len(target) == 0 {
+		target = link.OriginalURL
+		if variant := pickVariant(link.Variants, visit.Variant); variant != nil {
+			target = variant.URL
+			redirect.Variant = variant.ID
+		}
 	}
 
-	_ = s.caching.Set(ctx, hash, link.OriginalURL)
+	redirect.URL, err = destination(link, target, visit)
+	if err != nil {
+		return nil, err
+	}
 
-	return link.OriginalURL, nil
+	return redirect, nil
 }
 
-func (s *LinkService) Delete(ctx context.Context, hash string, userID string) error {
-	link, err := s.repo.FindByHash(ctx, hash)
+// Inspect summarizes where a link goes without counting it as a click.
+func (s *LinkService) Inspect(ctx context.Context, hash string) (*domain.LinkInfo, error) {
+	link, err := s.FindByHash(ctx, hash)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if link.UserID != userID {
-		return util.NewErrorf(util.ErrCodeUnauthorized, "user does not have permission")
+	clicks, err := s.clicks.Count(ctx, hash)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.repo.Delete(ctx, hash); err != nil {
+	// the owner name is informative only, so lookup failures are ignored,
+	// and anonymous links have no owner to look up
+	var owner string
+	if len(link.UserID) > 0 {
+		owner, _ = s.directory.DisplayName(ctx, link.UserID)
+	}
+
+	return &domain.LinkInfo{
+		Hash:         link.Hash,
+		OriginalURL:  link.OriginalURL,
+		Owner:        owner,
+		Clicks:       clicks,
+		ExpiresAt:    link.ExpiresAt,
+		CreationTime: link.CreationTime,
+	}, nil
+}
+
+// Get returns the link with its click count. Those who can view it in its
+// workspace, or its creator, see every attribute, even while it is disabled
+// or expired. Other callers only see the public attributes of links that
+// still redirect.
+func (s *LinkService) Get(ctx context.Context, hash string, principal *domain.Principal) (*domain.LinkDetails, error) {
+	if err := s.policy.CanRead(principal); err != nil {
+		return nil, err
+	}
+
+	link, err := s.findActive(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorize(ctx, link, principal, domain.RoleViewer); err != nil {
+		if !hasCode(err, util.ErrCodeForbidden) {
+			return nil, err
+		}
+
+		if link.Disabled {
+			return nil, util.NewErrorf(util.ErrCodeGone, "link is disabled")
+		}
+
+		if link.Expired(time.Now()) {
+			return nil, util.NewErrorf(util.ErrCodeGone, "link has expired")
+		}
+
+		link = link.Public()
+	}
+
+	clicks, err := s.clicks.Count(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.LinkDetails{Link: link, Clicks: clicks}, nil
+}
+
+func (s *LinkService) RegisterClick(ctx context.Context, hash string) error {
+	return s.clicks.Incr(ctx, hash)
+}
+
+// Delete moves the link to its owner's trash, from which it can be restored
+// until it is purged. Anonymous links have no owner to restore them and are
+// deleted permanently. A non-zero version must match the current one.
+func (s *LinkService) Delete(ctx context.Context, hash string, version int64, principal *domain.Principal) error {
+	if err := s.policy.CanWrite(principal); err != nil {
 		return err
 	}
 
-	_ = s.caching.Del(ctx, hash)
+	for attempt := 1; ; attempt++ {
+		link, err := s.findActive(ctx, hash)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		if err := s.authorize(ctx, link, principal, domain.RoleEditor); err != nil {
+			return err
+		}
+
+		if err := checkVersion(link, version); err != nil {
+			return err
+		}
+
+		before, err := snapshot(link)
+		if err != nil {
+			return err
+		}
+
+		if len(link.UserID) == 0 {
+			err = s.repo.Delete(ctx, hash)
+		} else {
+			err = s.repo.SoftDelete(ctx, link, time.Now())
+		}
+		if err != nil {
+			if version == 0 && attempt < maxUpdateAttempts && isPreconditionFailed(err) {
+				continue
+			}
+
+			return err
+		}
+
+		_ = s.caching.Del(ctx, hash)
+		_ = s.index.Remove(ctx, before)
+
+		recordAudit(ctx, s.audit, domain.AuditDelete, before, nil, principal)
+
+		return nil
+	}
 }
 
-func (s *LinkService) Update(ctx context.Context, hash string, newURL string, userID string) (*domain.Link, error) {
-	link, err := s.repo.FindByHash(ctx, hash)
+// Update replaces the destination settings of a link. Variants and the
+// social card are only replaced when provided, so an empty list or card is
+// needed to remove them. A non-zero version in changes must match the
+// current one.
+func (s *LinkService) Update(ctx context.Context, changes *domain.Link, principal *domain.Principal) (*domain.Link, error) {
+	if err := validateURL(changes.OriginalURL); err != nil {
+		return nil, err
+	}
+
+	if err := validateVariants(changes.Variants); err != nil {
+		return nil, err
+	}
+
+	if err := validateSocialCard(changes.SocialCard); err != nil {
+		return nil, err
+	}
+
+	var urlChanged bool
+
+	link, err := s.modify(ctx, changes.Hash, changes.Version, principal, func(link *domain.Link) error {
+		urlChanged = link.OriginalURL != changes.OriginalURL
+		if urlChanged {
+			link.Metadata = nil
+		}
+
+		link.OriginalURL = changes.OriginalURL
+		link.UTM = changes.UTM
+		link.ForwardQuery = changes.ForwardQuery
+		if changes.Variants != nil {
+			link.Variants = changes.Variants
+		}
+
+		if changes.SocialCard != nil {
+			link.SocialCard = changes.SocialCard
+			if link.SocialCard.IsZero() {
+				link.SocialCard = nil
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if link.UserID != userID {
-		return nil, util.NewErrorf(util.ErrCodeUnauthorized, "user does not have permission")
+	if urlChanged {
+		_ = s.previews.Enqueue(link)
 	}
 
-	if err := s.repo.Update(ctx, hash, newURL); err != nil {
+	return link, nil
+}
+
+// modify loads the link, checks that the principal is allowed to edit it
+// and persists the changes applied by fn. Changes made against a given
+// version fail if the link was modified since, otherwise conflicting
+// writes are retried on the latest version.
+func (s *LinkService) modify(ctx context.Context, hash string, version int64, principal *domain.Principal, fn func(link *domain.Link) error) (*domain.Link, error) {
+	if err := s.policy.CanWrite(principal); err != nil {
 		return nil, err
 	}
 
-	_ = s.caching.Set(ctx, hash, newURL)
+	for attempt := 1; ; attempt++ {
+		link, err := s.findActive(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
 
-	return &domain.Link{
-		Hash:         hash,
-		OriginalURL:  newURL,
-		UserID:       userID,
-		CreationTime: link.CreationTime,
-	}, nil
+		if err := s.authorize(ctx, link, principal, domain.RoleEditor); err != nil {
+			return nil, err
+		}
+
+		if err := checkVersion(link, version); err != nil {
+			return nil, err
+		}
+
+		before, err := snapshot(link)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := fn(link); err != nil {
+			return nil, err
+		}
+
+		if err := s.repo.Update(ctx, link); err != nil {
+			if version == 0 && attempt < maxUpdateAttempts && isPreconditionFailed(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		_ = s.caching.Set(ctx, link)
+		s.indexLink(ctx, link)
+
+		recordAudit(ctx, s.audit, domain.AuditUpdate, before, link, principal)
+
+		return link, nil
+	}
+}
+
+// validateURL checks that the destination of a link is an absolute url.
+func validateURL(originalURL string) error {
+	target, err := url.Parse(originalURL)
+	if err != nil || !target.IsAbs() || len(target.Host) == 0 {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "original url must be an absolute url")
+	}
+
+	return nil
+}
+
+// checkVersion fails unless the link is at the expected version. A zero
+// version expects none.
+func checkVersion(link *domain.Link, version int64) error {
+	if version != 0 && link.Version != version {
+		return util.NewErrorf(util.ErrCodePreconditionFailed, "link was modified, current version is %d", link.Version)
+	}
+
+	return nil
 }
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// linkFields are the attributes of a link that can be patched, as they
+// appear in the link's JSON.
+type linkFields struct {
+	OriginalURL  string                `json:"original_url"`
+	UTM          *domain.UTM           `json:"utm,omitempty"`
+	ForwardQuery bool                  `json:"forward_query"`
+	Rules        []domain.RedirectRule `json:"rules,omitempty"`
+	Variants     []domain.Variant      `json:"variants,omitempty"`
+	SocialCard   *domain.SocialCard    `json:"social_card,omitempty"`
+	Title        string                `json:"title,omitempty"`
+	Description  string                `json:"description,omitempty"`
+	Tags         []string              `json:"tags,omitempty"`
+	Notes        string                `json:"notes,omitempty"`
+	Disabled     bool                  `json:"disabled"`
+}
+
+// Patch applies a JSON Merge Patch (RFC 7396) to the editable attributes of
+// the link, so each one can be changed without resending the others. Null
+// members reset attributes and unknown or read-only members are rejected.
+// A non-zero version must match the current one.
+func (s *LinkService) Patch(ctx context.Context, hash string, patch []byte, version int64, principal *domain.Principal) (*domain.Link, error) {
+	var urlChanged bool
+
+	link, err := s.modify(ctx, hash, version, principal, func(link *domain.Link) error {
+		fields, err := applyPatch(link, patch)
+		if err != nil {
+			return err
+		}
+
+		if err := validatePatchedFields(fields); err != nil {
+			return err
+		}
+
+		urlChanged = link.OriginalURL != fields.OriginalURL
+		if urlChanged {
+			link.Metadata = nil
+		}
+
+		link.OriginalURL = fields.OriginalURL
+		link.UTM = fields.UTM
+		link.ForwardQuery = fields.ForwardQuery
+		link.Rules = fields.Rules
+		link.Variants = fields.Variants
+		link.SocialCard = fields.SocialCard
+		if link.SocialCard.IsZero() {
+			link.SocialCard = nil
+		}
+		link.Title = fields.Title
+		link.Description = fields.Description
+		link.Tags = fields.Tags
+		link.Notes = fields.Notes
+		link.Disabled = fields.Disabled
+
+		return validateAttributes(link)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if urlChanged {
+		_ = s.previews.Enqueue(link)
+	}
+
+	return link, nil
+}
+
+func applyPatch(link *domain.Link, patch []byte) (*linkFields, error) {
+	doc, err := json.Marshal(&linkFields{
+		OriginalURL:  link.OriginalURL,
+		UTM:          link.UTM,
+		ForwardQuery: link.ForwardQuery,
+		Rules:        link.Rules,
+		Variants:     link.Variants,
+		SocialCard:   link.SocialCard,
+		Title:        link.Title,
+		Description:  link.Description,
+		Tags:         link.Tags,
+		Notes:        link.Notes,
+		Disabled:     link.Disabled,
+	})
+	if err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeUnknown, "json marshal error")
+	}
+
+	merged, err := util.MergePatch(doc, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+
+	var fields linkFields
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "invalid patch: %v", err)
+	}
+
+	return &fields, nil
+}
+
+func validatePatchedFields(fields *linkFields) error {
+	if err := validateURL(fields.OriginalURL); err != nil {
+		return err
+	}
+
+	if err := validateRules(fields.Rules); err != nil {
+		return err
+	}
+
+	if err := validateVariants(fields.Variants); err != nil {
+		return err
+	}
+
+	return validateSocialCard(fields.SocialCard)
+}
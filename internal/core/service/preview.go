@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+)
+
+// Defaults of the worker settings left unset.
+const (
+	defaultPreviewWorkers   = 4
+	defaultPreviewQueueSize = 100
+	defaultPreviewTimeout   = 5 * time.Second
+)
+
+type previewJob struct {
+	hash string
+	url  string
+}
+
+// PreviewWorker fetches the metadata of link destinations in the background
+// with a bounded number of concurrent requests. Jobs are dropped when the
+// queue is full, since a missing preview doesn't affect redirection.
+type PreviewWorker struct {
+	fetcher port.MetadataFetcher
+	caching port.LinkCaching
+	repo    port.LinkRepository
+	workers int
+	timeout time.Duration
+	queue   chan previewJob
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewPreviewWorker(fetcher port.MetadataFetcher, caching port.LinkCaching, repo port.LinkRepository,
+	workers int, queueSize int, timeout time.Duration) *PreviewWorker {
+	if workers <= 0 {
+		workers = defaultPreviewWorkers
+	}
+
+	if queueSize <= 0 {
+		queueSize = defaultPreviewQueueSize
+	}
+
+	if timeout <= 0 {
+		timeout = defaultPreviewTimeout
+	}
+
+	return &PreviewWorker{
+		fetcher: fetcher,
+		caching: caching,
+		repo:    repo,
+		workers: workers,
+		timeout: timeout,
+		queue:   make(chan previewJob, queueSize),
+	}
+}
+
+func (w *PreviewWorker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	for i := 0; i < w.workers; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-w.queue:
+					w.process(ctx, job)
+				}
+			}
+		}()
+	}
+}
+
+// Stop cancels in-flight fetches and waits for the workers to exit.
+func (w *PreviewWorker) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+
+	w.wg.Wait()
+}
+
+func (w *PreviewWorker) Enqueue(link *domain.Link) bool {
+	select {
+	case w.queue <- previewJob{hash: link.Hash, url: link.OriginalURL}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *PreviewWorker) process(ctx context.Context, job previewJob) {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	metadata, err := w.fetcher.Fetch(ctx, job.url)
+	if err != nil {
+		return
+	}
+
+	// the destination may have changed while the page was being fetched,
+	// and the link may change again before the metadata is stored
+	for attempt := 1; ; attempt++ {
+		link, err := w.repo.FindByHash(ctx, job.hash)
+		if err != nil || link.OriginalURL != job.url {
+			return
+		}
+
+		err = w.repo.UpdateMetadata(ctx, link, metadata)
+		if err == nil {
+			break
+		}
+
+		if attempt >= maxUpdateAttempts || !isPreconditionFailed(err) {
+			return
+		}
+	}
+
+	_ = w.caching.Del(ctx, job.hash)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// destination builds the URL a visitor is redirected to from the selected
+// target. When the link forwards queries, the incoming path suffix is
+// appended to the target path and incoming parameters are added unless the
+// target already defines them. UTM parameters configured on the link always
+// take precedence.
+func destination(link *domain.Link, targetURL string, visit *domain.Visit) (string, error) {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return "", util.WrapErrorf(err, util.ErrCodeUnknown, "invalid destination url")
+	}
+
+	query := target.Query()
+	utm := link.UTM.Values()
+
+	if link.ForwardQuery {
+		if len(visit.Suffix) > 0 {
+			target.Path = strings.TrimSuffix(target.Path, "/") + "/" + visit.Suffix
+			target.RawPath = ""
+		}
+
+		for key, values := range visit.Query {
+			if _, ok := query[key]; !ok {
+				query[key] = values
+			}
+		}
+	}
+
+	for key, values := range utm {
+		query[key] = values
+	}
+
+	if (link.ForwardQuery && len(visit.Query) > 0) || len(utm) > 0 {
+		target.RawQuery = query.Encode()
+	}
+
+	return target.String(), nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+func (s *LinkService) ListRules(ctx context.Context, hash string, principal *domain.Principal) ([]domain.RedirectRule, error) {
+	if err := s.policy.CanRead(principal); err != nil {
+		return nil, err
+	}
+
+	link, err := s.findActive(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorize(ctx, link, principal, domain.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return link.Rules, nil
+}
+
+func (s *LinkService) ReplaceRules(ctx context.Context, hash string, rules []domain.RedirectRule, principal *domain.Principal) (*domain.Link, error) {
+	if err := validateRules(rules); err != nil {
+		return nil, err
+	}
+
+	return s.modify(ctx, hash, 0, principal, func(link *domain.Link) error {
+		link.Rules = rules
+		return nil
+	})
+}
+
+func (s *LinkService) AddRule(ctx context.Context, hash string, rule domain.RedirectRule, principal *domain.Principal) (*domain.Link, error) {
+	if err := validateRules([]domain.RedirectRule{rule}); err != nil {
+		return nil, err
+	}
+
+	return s.modify(ctx, hash, 0, principal, func(link *domain.Link) error {
+		link.Rules = append(link.Rules, rule)
+		return nil
+	})
+}
+
+func (s *LinkService) RemoveRule(ctx context.Context, hash string, index int, principal *domain.Principal) (*domain.Link, error) {
+	return s.modify(ctx, hash, 0, principal, func(link *domain.Link) error {
+		if index < 0 || index >= len(link.Rules) {
+			return util.NewErrorf(util.ErrCodeNotFound, "rule not found")
+		}
+
+		link.Rules = append(link.Rules[:index], link.Rules[index+1:]...)
+		return nil
+	})
+}
+
+func validateRules(rules []domain.RedirectRule) error {
+	for i, rule := range rules {
+		target, err := url.Parse(rule.Target)
+		if err != nil || !target.IsAbs() || len(target.Host) == 0 {
+			return util.NewErrorf(util.ErrCodeInvalidArgument, "rule %d: invalid target url", i)
+		}
+
+		for _, device := range rule.Conditions.Device {
+			switch strings.ToLower(device) {
+			case domain.DeviceMobile, domain.DeviceTablet, domain.DeviceDesktop, domain.DeviceBot:
+			default:
+				return util.NewErrorf(util.ErrCodeInvalidArgument, "rule %d: unknown device %q", i, device)
+			}
+		}
+
+		c := rule.Conditions
+		if c.NotBefore != nil && c.NotAfter != nil && c.NotAfter.Before(*c.NotBefore) {
+			return util.NewErrorf(util.ErrCodeInvalidArgument, "rule %d: time window ends before it starts", i)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+
+	// maxSearchFetch bounds how many entries are read from the index to
+	// make up for those of links that changed since they were indexed.
+	maxSearchFetch = 4 * maxSearchLimit
+)
+
+// Search finds the personal links of the principal or, when the query has
+// a workspace, the links of a workspace the principal is a member of.
+func (s *LinkService) Search(ctx context.Context, query *domain.LinkQuery, principal *domain.Principal) ([]*domain.Link, error) {
+	if err := s.policy.CanRead(principal); err != nil {
+		return nil, err
+	}
+
+	q := *query
+	q.UserID = principal.UserID
+	q.Domain = strings.ToLower(strings.TrimSpace(q.Domain))
+	q.Title = strings.TrimSpace(q.Title)
+
+	if len(q.WorkspaceID) > 0 && !s.policy.IsAdmin(principal) {
+		if err := authorizeWorkspace(ctx, s.workspaces, q.WorkspaceID, principal.UserID, domain.RoleViewer); err != nil {
+			return nil, err
+		}
+	}
+
+	tags, err := normalizeTags(q.Tags)
+	if err != nil {
+		return nil, err
+	}
+	q.Tags = tags
+
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "since must be before until")
+	}
+
+	switch {
+	case q.Limit <= 0:
+		q.Limit = defaultSearchLimit
+	case q.Limit > maxSearchLimit:
+		q.Limit = maxSearchLimit
+	}
+
+	// the index may lag behind, so the links are checked again, and more
+	// entries are read while some of them are skipped
+	checked := make(map[string]*domain.Link)
+	for fetch := q.Limit; ; fetch *= 2 {
+		if fetch > maxSearchFetch {
+			fetch = maxSearchFetch
+		}
+
+		indexQuery := q
+		indexQuery.Limit = fetch
+
+		hashes, err := s.index.Search(ctx, &indexQuery)
+		if err != nil {
+			return nil, err
+		}
+
+		links := make([]*domain.Link, 0, q.Limit)
+		for _, hash := range hashes {
+			link, ok := checked[hash]
+			if !ok {
+				if link, err = s.matchingLink(ctx, hash, &q); err != nil {
+					return nil, err
+				}
+				checked[hash] = link
+			}
+
+			if link != nil {
+				links = append(links, link)
+			}
+
+			if len(links) == q.Limit {
+				break
+			}
+		}
+
+		if len(links) == q.Limit || len(hashes) < fetch || fetch == maxSearchFetch {
+			return links, nil
+		}
+	}
+}
+
+// matchingLink loads a link found in the index, or returns nil if it no
+// longer matches the query.
+func (s *LinkService) matchingLink(ctx context.Context, hash string, q *domain.LinkQuery) (*domain.Link, error) {
+	link, err := s.repo.FindByHash(ctx, hash)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	if link.Deleted() || domain.LinkOwner(link) != q.Owner() || !q.Matches(link) {
+		return nil, nil
+	}
+
+	return link, nil
+}
+
+// indexLink updates the search index. The index is derived from the links,
+// like the cache, so failing to update it doesn't fail the change, and
+// Reindex catches up with the links whose indexing failed.
+func (s *LinkService) indexLink(ctx context.Context, link *domain.Link) {
+	if !searchable(link) {
+		return
+	}
+
+	_ = s.index.Index(ctx, link)
+}
+
+// Reindex rebuilds the index of every owner of a link, such as the links
+// created before the index or whose indexing failed, and returns how many
+// links were indexed. The entries of each owner are cleared first, so
+// those of links deleted or changed since they were indexed are dropped.
+func Reindex(ctx context.Context, links port.LinkRepository, index port.LinkIndex) (int, error) {
+	var (
+		owners  []string
+		byOwner = make(map[string][]*domain.Link)
+	)
+
+	if err := links.Each(ctx, func(link *domain.Link) error {
+		if len(link.UserID) == 0 && len(link.WorkspaceID) == 0 {
+			return nil
+		}
+
+		owner := domain.LinkOwner(link)
+		if _, ok := byOwner[owner]; !ok {
+			owners = append(owners, owner)
+			byOwner[owner] = nil
+		}
+
+		if searchable(link) {
+			byOwner[owner] = append(byOwner[owner], link)
+		}
+
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	indexed := 0
+	for _, owner := range owners {
+		if err := index.Clear(ctx, owner); err != nil {
+			return indexed, err
+		}
+
+		for _, link := range byOwner[owner] {
+			if err := index.Index(ctx, link); err != nil {
+				return indexed, err
+			}
+
+			indexed++
+		}
+	}
+
+	return indexed, nil
+}
+
+// searchable reports whether the link belongs in the search index.
+// Anonymous links have no owner to search them.
+func searchable(link *domain.Link) bool {
+	return !link.Anonymous && len(link.UserID) > 0 && !link.Deleted()
+}
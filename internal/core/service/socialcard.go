@@ -0,0 +1,36 @@
+package service
+
+import (
+	"net/url"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const (
+	maxSocialCardTitle       = 200
+	maxSocialCardDescription = 1000
+)
+
+func validateSocialCard(card *domain.SocialCard) error {
+	if card == nil {
+		return nil
+	}
+
+	if len(card.Title) > maxSocialCardTitle {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "social card title exceeds %d characters", maxSocialCardTitle)
+	}
+
+	if len(card.Description) > maxSocialCardDescription {
+		return util.NewErrorf(util.ErrCodeInvalidArgument, "social card description exceeds %d characters", maxSocialCardDescription)
+	}
+
+	if len(card.Image) > 0 {
+		image, err := url.Parse(card.Image)
+		if err != nil || (image.Scheme != "http" && image.Scheme != "https") || len(image.Host) == 0 {
+			return util.NewErrorf(util.ErrCodeInvalidArgument, "social card image must be an absolute http(s) url")
+		}
+	}
+
+	return nil
+}
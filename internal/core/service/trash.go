@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// defaultTrashRetention is how long deleted links can be restored when no
+// retention is configured. Without a retention, links would be purged as
+// soon as they are deleted.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// findActive loads a link that can still be managed, reporting deleted
+// links as gone like FindByHash. Expired links can still be managed.
+func (s *LinkService) findActive(ctx context.Context, hash string) (*domain.Link, error) {
+	link, err := s.repo.FindByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.Deleted() {
+		return nil, util.NewErrorf(util.ErrCodeGone, "link has been deleted")
+	}
+
+	return link, nil
+}
+
+// Trash lists the deleted links of the principal that can still be
+// restored, most recently deleted first.
+func (s *LinkService) Trash(ctx context.Context, principal *domain.Principal) ([]*domain.Link, error) {
+	if err := s.policy.CanRead(principal); err != nil {
+		return nil, err
+	}
+
+	deleted, err := s.repo.ListDeleted(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	links := make([]*domain.Link, 0, len(deleted))
+	for _, link := range deleted {
+		if s.restorable(link, now) {
+			links = append(links, link)
+		}
+	}
+
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].DeletedAt.After(*links[j].DeletedAt)
+	})
+
+	return links, nil
+}
+
+// Restore takes a link out of the trash while it is within the retention
+// window.
+func (s *LinkService) Restore(ctx context.Context, hash string, principal *domain.Principal) (*domain.Link, error) {
+	if err := s.policy.CanWrite(principal); err != nil {
+		return nil, err
+	}
+
+	link, err := s.repo.FindByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorize(ctx, link, principal, domain.RoleEditor); err != nil {
+		return nil, err
+	}
+
+	if !link.Deleted() {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "link is not deleted")
+	}
+
+	if !s.restorable(link, time.Now()) {
+		return nil, util.NewErrorf(util.ErrCodeGone, "link can no longer be restored")
+	}
+
+	before, err := snapshot(link)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Restore(ctx, link); err != nil {
+		return nil, err
+	}
+
+	link.DeletedAt = nil
+	_ = s.caching.Del(ctx, hash)
+	s.indexLink(ctx, link)
+
+	recordAudit(ctx, s.audit, domain.AuditRestore, before, link, principal)
+
+	return link, nil
+}
+
+func (s *LinkService) restorable(link *domain.Link, now time.Time) bool {
+	return link.Deleted() && now.Before(link.DeletedAt.Add(s.retention))
+}
+
+// TrashPurger permanently deletes the links kept in the trash longer than
+// the retention period. Purging is idempotent, so every replica can run it.
+type TrashPurger struct {
+	repo      port.LinkRepository
+	caching   port.LinkCaching
+	audit     port.AuditRepository
+	retention time.Duration
+	interval  time.Duration
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func NewTrashPurger(repo port.LinkRepository, caching port.LinkCaching, audit port.AuditRepository,
+	retention time.Duration, interval time.Duration) *TrashPurger {
+	if retention <= 0 {
+		retention = defaultTrashRetention
+	}
+
+	return &TrashPurger{
+		repo:      repo,
+		caching:   caching,
+		audit:     audit,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Start purges the trash every interval until stopped. Purging is
+// disabled without an interval.
+func (p *TrashPurger) Start() {
+	if p.interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_, _ = p.Purge(ctx, time.Now())
+			}
+		}
+	}()
+}
+
+// Stop cancels a purge in progress and waits for it to exit.
+func (p *TrashPurger) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+
+	p.wg.Wait()
+}
+
+// Purge deletes the links whose retention ended before now and returns how
+// many were deleted.
+func (p *TrashPurger) Purge(ctx context.Context, now time.Time) (int, error) {
+	expired, err := p.repo.ListDeletedBefore(ctx, now.Add(-p.retention))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, entry := range expired {
+		// the trash may be indexed apart from the link, so the link is
+		// read again in case it was restored since, and only purged while
+		// it is unchanged
+		link, err := p.repo.FindByHash(ctx, entry.Hash)
+		if err != nil && !isNotFound(err) {
+			return purged, err
+		}
+
+		switch {
+		case link == nil:
+			link = entry
+			err = p.repo.Delete(ctx, link.Hash)
+		case link.Deleted():
+			err = p.repo.Purge(ctx, link)
+		default:
+			continue
+		}
+		if err != nil {
+			if isPreconditionFailed(err) || isNotFound(err) {
+				continue
+			}
+
+			return purged, err
+		}
+
+		_ = p.caching.Del(ctx, link.Hash)
+		purged++
+
+		recordAudit(ctx, p.audit, domain.AuditPurge, link, nil, nil)
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"math/rand"
+	"net/url"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// pickVariant keeps the visitor on the variant stored in their cookie while
+// it still receives traffic, otherwise draws one according to the weights.
+func pickVariant(variants []domain.Variant, assigned string) *domain.Variant {
+	total := 0
+	for i := range variants {
+		if variants[i].ID == assigned && variants[i].Weight > 0 {
+			return &variants[i]
+		}
+
+		total += variants[i].Weight
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	n := rand.Intn(total)
+	for i := range variants {
+		if n < variants[i].Weight {
+			return &variants[i]
+		}
+
+		n -= variants[i].Weight
+	}
+
+	return nil
+}
+
+func validateVariants(variants []domain.Variant) error {
+	ids := make(map[string]bool, len(variants))
+
+	for i, variant := range variants {
+		if len(variant.ID) == 0 {
+			return util.NewErrorf(util.ErrCodeInvalidArgument, "variant %d: id is required", i)
+		}
+
+		if ids[variant.ID] {
+			return util.NewErrorf(util.ErrCodeInvalidArgument, "variant %d: duplicated id %q", i, variant.ID)
+		}
+		ids[variant.ID] = true
+
+		target, err := url.Parse(variant.URL)
+		if err != nil || !target.IsAbs() || len(target.Host) == 0 {
+			return util.NewErrorf(util.ErrCodeInvalidArgument, "variant %d: invalid url", i)
+		}
+
+		if variant.Weight < 0 {
+			return util.NewErrorf(util.ErrCodeInvalidArgument, "variant %d: weight must not be negative", i)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/policy"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const maxWorkspaceName = 100
+
+type WorkspaceService struct {
+	repo   port.WorkspaceRepository
+	policy *policy.Policy
+}
+
+func NewWorkspaceService(repo port.WorkspaceRepository, policy *policy.Policy) port.WorkspaceService {
+	return &WorkspaceService{
+		repo:   repo,
+		policy: policy,
+	}
+}
+
+func (s *WorkspaceService) Create(ctx context.Context, name string, principal *domain.Principal) (*domain.Workspace, error) {
+	if err := s.policy.CanWrite(principal); err != nil {
+		return nil, err
+	}
+
+	userID := principal.UserID
+	name = strings.TrimSpace(name)
+	if len(name) == 0 || len(name) > maxWorkspaceName {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "workspace name must have between 1 and %d characters", maxWorkspaceName)
+	}
+
+	id, err := util.NewUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	workspace := &domain.Workspace{
+		ID:           id,
+		Name:         name,
+		CreatedBy:    userID,
+		CreationTime: now,
+		Role:         domain.RoleOwner,
+	}
+
+	if err := s.repo.Create(ctx, workspace, &domain.Member{
+		WorkspaceID: id,
+		UserID:      userID,
+		Role:        domain.RoleOwner,
+		JoinedAt:    now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+// Get returns a workspace of the principal, with their role. Admins can get
+// any workspace, without a role unless they are members.
+func (s *WorkspaceService) Get(ctx context.Context, id string, principal *domain.Principal) (*domain.Workspace, error) {
+	if err := s.policy.CanRead(principal); err != nil {
+		return nil, err
+	}
+
+	member, err := s.authorize(ctx, id, principal, domain.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if member != nil {
+		workspace.Role = member.Role
+	}
+
+	return workspace, nil
+}
+
+func (s *WorkspaceService) List(ctx context.Context, principal *domain.Principal) ([]*domain.Workspace, error) {
+	if err := s.policy.CanRead(principal); err != nil {
+		return nil, err
+	}
+
+	memberships, err := s.repo.ListByUser(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaces := make([]*domain.Workspace, 0, len(memberships))
+	for _, member := range memberships {
+		workspace, err := s.repo.FindByID(ctx, member.WorkspaceID)
+		if err != nil {
+			return nil, err
+		}
+
+		workspace.Role = member.Role
+		workspaces = append(workspaces, workspace)
+	}
+
+	return workspaces, nil
+}
+
+func (s *WorkspaceService) ListMembers(ctx context.Context, id string, principal *domain.Principal) ([]*domain.Member, error) {
+	if err := s.policy.CanRead(principal); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.authorize(ctx, id, principal, domain.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListMembers(ctx, id)
+}
+
+// SetMember adds a user to the workspace or changes their role.
+func (s *WorkspaceService) SetMember(ctx context.Context, id string, memberID string, role string, principal *domain.Principal) (*domain.Member, error) {
+	if err := s.policy.CanWrite(principal); err != nil {
+		return nil, err
+	}
+
+	if !domain.ValidRole(role) {
+		return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "unknown role %q", role)
+	}
+
+	if _, err := s.authorize(ctx, id, principal, domain.RoleOwner); err != nil {
+		return nil, err
+	}
+
+	member, err := s.repo.FindMember(ctx, id, memberID)
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+
+	if member == nil {
+		member = &domain.Member{
+			WorkspaceID: id,
+			UserID:      memberID,
+			JoinedAt:    time.Now(),
+		}
+	} else if member.Role == domain.RoleOwner && role != domain.RoleOwner {
+		if err := s.ensureAnotherOwner(ctx, id, memberID); err != nil {
+			return nil, err
+		}
+	}
+
+	member.Role = role
+	if err := s.repo.SaveMember(ctx, member); err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// RemoveMember removes a user from the workspace. Owners can remove anyone
+// and every member can leave, as long as the workspace keeps an owner.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, id string, memberID string, principal *domain.Principal) error {
+	if err := s.policy.CanWrite(principal); err != nil {
+		return err
+	}
+
+	required := domain.RoleOwner
+	if memberID == principal.UserID {
+		required = domain.RoleViewer
+	}
+
+	if _, err := s.authorize(ctx, id, principal, required); err != nil {
+		return err
+	}
+
+	member, err := s.repo.FindMember(ctx, id, memberID)
+	if err != nil {
+		return err
+	}
+
+	if member.Role == domain.RoleOwner {
+		if err := s.ensureAnotherOwner(ctx, id, memberID); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.DeleteMember(ctx, id, memberID)
+}
+
+// authorize returns the membership of the principal, which must have at
+// least the required role. Admins are authorized for every existing
+// workspace, and their membership is nil if they aren't members.
+func (s *WorkspaceService) authorize(ctx context.Context, id string, principal *domain.Principal, required string) (*domain.Member, error) {
+	member, err := s.repo.FindMember(ctx, id, principal.UserID)
+	if err != nil && !isNotFound(err) {
+		return nil, err
+	}
+
+	if s.policy.IsAdmin(principal) {
+		if member == nil {
+			if _, err := s.repo.FindByID(ctx, id); err != nil {
+				return nil, err
+			}
+		}
+
+		return member, nil
+	}
+
+	if member == nil {
+		return nil, util.NewErrorf(util.ErrCodeNotFound, "workspace not found")
+	}
+
+	if !domain.RoleAllows(member.Role, required) {
+		return nil, util.NewErrorf(util.ErrCodeForbidden, "user does not have permission")
+	}
+
+	return member, nil
+}
+
+func (s *WorkspaceService) ensureAnotherOwner(ctx context.Context, id string, userID string) error {
+	members, err := s.repo.ListMembers(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if member.Role == domain.RoleOwner && member.UserID != userID {
+			return nil
+		}
+	}
+
+	return util.NewErrorf(util.ErrCodeInvalidArgument, "workspace must have at least one owner")
+}
@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+type APIKeyHandler struct {
+	auth port.Auth
+	svc  port.APIKeyService
+}
+
+func NewAPIKeyHandler(auth port.Auth, svc port.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		auth: auth,
+		svc:  svc,
+	}
+}
+
+func (h *APIKeyHandler) Register(r *mux.Router) {
+	r.HandleFunc("/api/keys", h.create).Methods(http.MethodPost)
+	r.HandleFunc("/api/keys", h.list).Methods(http.MethodGet)
+	r.HandleFunc("/api/keys/{id}", h.revoke).Methods(http.MethodDelete)
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse is the only response including the key itself.
+type CreateAPIKeyResponse struct {
+	*domain.APIKey
+	Key string `json:"key"`
+}
+
+func (h *APIKeyHandler) create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "json decode"),
+			"Invalid request format")
+		return
+	}
+
+	key, plain, err := h.svc.Create(r.Context(), &domain.APIKey{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}, principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(&CreateAPIKeyResponse{APIKey: key, Key: plain})
+}
+
+func (h *APIKeyHandler) list(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	keys, err := h.svc.List(r.Context(), principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	if keys == nil {
+		keys = []*domain.APIKey{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&keys)
+}
+
+func (h *APIKeyHandler) revoke(w http.ResponseWriter, r *http.Request) {
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	if err := h.svc.Revoke(r.Context(), mux.Vars(r)["id"], principal); err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,148 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const maxRequestIDLength = 128
+
+type AuditHandler struct {
+	auth port.Auth
+	svc  port.AuditService
+}
+
+func NewAuditHandler(auth port.Auth, svc port.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auth: auth,
+		svc:  svc,
+	}
+}
+
+func (h *AuditHandler) Register(r *mux.Router) {
+	r.HandleFunc("/api/audit", h.query).Methods(http.MethodGet)
+	r.HandleFunc("/api/shortlink/{hash}/history", h.history).Methods(http.MethodGet)
+}
+
+func (h *AuditHandler) history(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		handleError(w, err, "Invalid audit query")
+		return
+	}
+
+	events, err := h.svc.History(r.Context(), mux.Vars(r)["hash"], filter.Limit, principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	writeAuditEvents(w, events)
+}
+
+// query searches the whole audit trail. It accepts the hash, actor, action,
+// since, until and limit parameters, with times in RFC 3339.
+func (h *AuditHandler) query(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		handleError(w, err, "Invalid audit query")
+		return
+	}
+
+	events, err := h.svc.Query(r.Context(), filter, principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	writeAuditEvents(w, events)
+}
+
+func writeAuditEvents(w http.ResponseWriter, events []*domain.AuditEvent) {
+	if events == nil {
+		events = []*domain.AuditEvent{}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&events)
+}
+
+func parseAuditFilter(r *http.Request) (*domain.AuditFilter, error) {
+	query := r.URL.Query()
+
+	filter := &domain.AuditFilter{
+		Hash:   query.Get("hash"),
+		Actor:  query.Get("actor"),
+		Action: query.Get("action"),
+	}
+
+	if v := query.Get("limit"); len(v) > 0 {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "limit must be a positive number")
+		}
+		filter.Limit = limit
+	}
+
+	var err error
+	if v := query.Get("since"); len(v) > 0 {
+		if filter.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "since must be an RFC 3339 time")
+		}
+	}
+
+	if v := query.Get("until"); len(v) > 0 {
+		if filter.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "until must be an RFC 3339 time")
+		}
+	}
+
+	return filter, nil
+}
+
+// RequestMetadataMiddleware stores the metadata recorded in the audit trail
+// in the request context. The request ID is taken from the X-Request-ID
+// header, set by proxies, or generated, and echoed in the response.
+func RequestMetadataMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := strings.TrimSpace(r.Header.Get("X-Request-ID"))
+		if len(requestID) == 0 || len(requestID) > maxRequestIDLength {
+			requestID, _ = util.NewUUID()
+		}
+
+		w.Header().Set("X-Request-ID", requestID)
+
+		ctx := domain.ContextWithRequestMetadata(r.Context(), &domain.RequestMetadata{
+			RequestID: requestID,
+			IP:        clientIP(r),
+			UserAgent: r.UserAgent(),
+		})
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -30,6 +30,14 @@ func handleError(w http.ResponseWriter, err error, message string) {
 			response.Code = http.StatusNotFound
 		case util.ErrCodeUnauthorized:
 			response.Code = http.StatusUnauthorized
+		case util.ErrCodeForbidden:
+			response.Code = http.StatusForbidden
+		case util.ErrCodeGone:
+			response.Code = http.StatusGone
+		case util.ErrCodeTooManyRequests:
+			response.Code = http.StatusTooManyRequests
+		case util.ErrCodePreconditionFailed:
+			response.Code = http.StatusPreconditionFailed
 		case util.ErrCodeUnknown:
 			response.Code = http.StatusBadRequest
 		}
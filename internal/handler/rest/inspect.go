@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// inspectSuffix appended to a short link shows its details instead of
+// redirecting, e.g. /aBcDeFg+
+const inspectSuffix = "+"
+
+var inspectTemplate = template.Must(template.New("inspect").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{.Hash}}</title>
+</head>
+<body>
+<h1>{{.Hash}}</h1>
+<dl>
+<dt>Destination</dt><dd><a href="{{.OriginalURL}}" rel="noopener noreferrer nofollow">{{.OriginalURL}}</a></dd>
+<dt>Created</dt><dd>{{.CreationTime.Format "2006-01-02 15:04 MST"}}</dd>
+{{with .Owner}}<dt>Owner</dt><dd>{{.}}</dd>
+{{end}}<dt>Clicks</dt><dd>{{.Clicks}}</dd>
+</dl>
+</body>
+</html>
+`))
+
+func wantsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+func (h *LinkHandler) inspect(w http.ResponseWriter, r *http.Request, hash string) {
+	info, err := h.svc.Inspect(r.Context(), hash)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(&info)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = inspectTemplate.Execute(w, info)
+}
@@ -1,9 +1,11 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
-	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -13,6 +15,12 @@ import (
 	"github.com/mileusna/useragent"
 )
 
+// apiKeyHeader carries the API keys of machine clients.
+const apiKeyHeader = "X-API-Key"
+
+// variantCookieMaxAge keeps visitors on the same variant for 30 days.
+const variantCookieMaxAge = 30 * 24 * 60 * 60
+
 type LinkHandler struct {
 	auth     port.Auth
 	producer port.MetricsProducer
@@ -28,28 +36,70 @@ func NewLinkHandler(auth port.Auth, producer port.MetricsProducer, svc port.Link
 }
 
 func (h *LinkHandler) Register(r *mux.Router) {
-	r.HandleFunc("/{hash}", h.show).Methods(http.MethodGet)
 	r.HandleFunc("/api/shortlink", h.create).Methods(http.MethodPost)
+	r.HandleFunc("/api/shortlink/trash", h.trash).Methods(http.MethodGet)
+	r.HandleFunc("/api/shortlink/search", h.search).Methods(http.MethodGet)
+	r.HandleFunc("/api/shortlink/{hash}", h.get).Methods(http.MethodGet)
+	r.HandleFunc("/api/shortlink/{hash}/restore", h.restore).Methods(http.MethodPost)
 	r.HandleFunc("/api/shortlink/{hash}", h.update).Methods(http.MethodPut)
+	r.HandleFunc("/api/shortlink/{hash}", h.patch).Methods(http.MethodPatch)
 	r.HandleFunc("/api/shortlink/{hash}", h.delete).Methods(http.MethodDelete)
+	r.HandleFunc("/api/shortlink/{hash}/rules", h.listRules).Methods(http.MethodGet)
+	r.HandleFunc("/api/shortlink/{hash}/rules", h.replaceRules).Methods(http.MethodPut)
+	r.HandleFunc("/api/shortlink/{hash}/rules", h.addRule).Methods(http.MethodPost)
+	r.HandleFunc("/api/shortlink/{hash}/rules/{index:[0-9]+}", h.removeRule).Methods(http.MethodDelete)
+	r.HandleFunc("/{hash}", h.show).Methods(http.MethodGet)
+	r.HandleFunc("/{hash}/{suffix:.*}", h.show).Methods(http.MethodGet)
 }
 
 func (h *LinkHandler) show(w http.ResponseWriter, r *http.Request) {
-	hash := mux.Vars(r)["hash"]
+	vars := mux.Vars(r)
+	hash := vars["hash"]
+
+	if len(vars["suffix"]) == 0 && (strings.HasSuffix(hash, inspectSuffix) || wantsJSON(r)) {
+		h.inspect(w, r, strings.TrimSuffix(hash, inspectSuffix))
+		return
+	}
+
+	userAgent := useragent.Parse(r.Header.Get("User-Agent"))
 
-	url, err := h.svc.FindByHash(r.Context(), hash)
+	visit := newVisit(r, userAgent, vars["suffix"])
+	if cookie, err := r.Cookie(variantCookieName(hash)); err == nil {
+		visit.Variant = cookie.Value
+	}
+
+	redirect, err := h.svc.Resolve(r.Context(), hash, visit)
 	if err != nil {
 		handleError(w, err, "An internal error has occurred. Please try again later.")
 		return
 	}
 
+	if len(redirect.Variant) > 0 && redirect.Variant != visit.Variant {
+		http.SetCookie(w, &http.Cookie{
+			Name:     variantCookieName(hash),
+			Value:    redirect.Variant,
+			Path:     "/" + hash,
+			MaxAge:   variantCookieMaxAge,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	// crawlers fetching the social card aren't visitors, so they aren't
+	// counted as clicks
+	if redirect.Link.SocialCard != nil && isPreviewCrawler(userAgent.String) {
+		renderSocialCard(w, redirect)
+		return
+	}
+
 	go func() {
-		userIP, _, _ := net.SplitHostPort(r.RemoteAddr)
-		userAgent := useragent.Parse(r.Header.Get("User-Agent"))
+		userIP := clientIP(r)
+
+		_ = h.svc.RegisterClick(context.Background(), hash)
 
 		_ = h.producer.Produce(&domain.LinkMetrics{
 			ShortURL:       hash,
-			OriginalURL:    url,
+			OriginalURL:    redirect.URL,
 			IPAddress:      userIP,
 			Referer:        r.Referer(),
 			Device:         userAgent.Device,
@@ -59,21 +109,47 @@ func (h *LinkHandler) show(w http.ResponseWriter, r *http.Request) {
 			UserAgentName:  userAgent.Name,
 			Version:        userAgent.Version,
 			AcceptLanguage: r.Header.Get("Accept-Language"),
+			Variant:        redirect.Variant,
 			AccessTime:     time.Now(),
 		})
 	}()
 
-	http.Redirect(w, r, url, http.StatusFound)
+	http.Redirect(w, r, redirect.URL, http.StatusFound)
+}
+
+func variantCookieName(hash string) string {
+	return "sl_variant_" + hash
 }
 
 type CreateLinkRequest struct {
-	OriginalURL string `json:"original_url"`
+	OriginalURL  string                `json:"original_url"`
+	WorkspaceID  string                `json:"workspace_id"`
+	UTM          *domain.UTM           `json:"utm"`
+	ForwardQuery bool                  `json:"forward_query"`
+	Rules        []domain.RedirectRule `json:"rules"`
+	Variants     []domain.Variant      `json:"variants"`
+	SocialCard   *domain.SocialCard    `json:"social_card"`
+	Title        string                `json:"title"`
+	Description  string                `json:"description"`
+	Tags         []string              `json:"tags"`
+	Notes        string                `json:"notes"`
+	ExpiresAt    *time.Time            `json:"expires_at"`
+	// Verification is the CAPTCHA response or proof of work required to
+	// create links without credentials.
+	Verification string `json:"verification"`
 }
 
+// create shortens a link for the authenticated caller, or anonymously when
+// the request carries no credentials at all.
 func (h *LinkHandler) create(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	userID, err := h.auth.Authenticate(r, w)
+	if !hasCredentials(r) {
+		h.createAnonymous(w, r)
+		return
+	}
+
+	principal, err := h.auth.Authenticate(r, w)
 	if err != nil {
 		handleError(w, err, "Invalid authentication credentials")
 		return
@@ -86,29 +162,108 @@ func (h *LinkHandler) create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	link, err := h.svc.Create(r.Context(), req.OriginalURL, userID)
+	link, err := h.svc.Create(r.Context(), &domain.Link{
+		OriginalURL:  req.OriginalURL,
+		WorkspaceID:  req.WorkspaceID,
+		UTM:          req.UTM,
+		ForwardQuery: req.ForwardQuery,
+		Rules:        req.Rules,
+		Variants:     req.Variants,
+		SocialCard:   req.SocialCard,
+		Title:        req.Title,
+		Description:  req.Description,
+		Tags:         req.Tags,
+		Notes:        req.Notes,
+		ExpiresAt:    req.ExpiresAt,
+	}, principal)
 	if err != nil {
 		handleError(w, err, "An internal error has occurred. Please try again later.")
 		return
 	}
 
+	setETag(w, link)
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(&link)
 }
 
+func (h *LinkHandler) createAnonymous(w http.ResponseWriter, r *http.Request) {
+	var req CreateLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "json decode"),
+			"Invalid request format")
+		return
+	}
+
+	link, err := h.svc.CreateAnonymous(r.Context(), &domain.Link{
+		OriginalURL: req.OriginalURL,
+		UTM:         req.UTM,
+		ExpiresAt:   req.ExpiresAt,
+	}, &domain.AnonymousClient{
+		IP:           clientIP(r),
+		Verification: req.Verification,
+	})
+	if err != nil {
+		handleError(w, err, "The link could not be created without authentication")
+		return
+	}
+
+	setETag(w, link)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(&link)
+}
+
+// hasCredentials reports whether the request carries a bearer token or an
+// API key, valid or not.
+func hasCredentials(r *http.Request) bool {
+	return len(r.Header.Get("Authorization")) > 0 || len(r.Header.Get(apiKeyHeader)) > 0
+}
+
 type UpdateLinkRequest struct {
-	OriginalURL string `json:"original_url"`
+	OriginalURL  string             `json:"original_url"`
+	UTM          *domain.UTM        `json:"utm"`
+	ForwardQuery bool               `json:"forward_query"`
+	Variants     []domain.Variant   `json:"variants"`
+	SocialCard   *domain.SocialCard `json:"social_card"`
+}
+
+// get returns the link with its click count. Callers who don't manage the
+// link only get its public attributes.
+func (h *LinkHandler) get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	details, err := h.svc.Get(r.Context(), mux.Vars(r)["hash"], principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	setETag(w, details.Link)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&details)
 }
 
 func (h *LinkHandler) update(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	userID, err := h.auth.Authenticate(r, w)
+	principal, err := h.auth.Authenticate(r, w)
 	if err != nil {
 		handleError(w, err, "Invalid authentication credentials")
 		return
 	}
 
+	version, err := parseIfMatch(r)
+	if err != nil {
+		handleError(w, err, "The link was modified since it was retrieved")
+		return
+	}
+
 	var req UpdateLinkRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		handleError(w, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "json decode"),
@@ -117,26 +272,76 @@ func (h *LinkHandler) update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	vars := mux.Vars(r)
-	link, err := h.svc.Update(r.Context(), vars["hash"], req.OriginalURL, userID)
+	link, err := h.svc.Update(r.Context(), &domain.Link{
+		Hash:         vars["hash"],
+		Version:      version,
+		OriginalURL:  req.OriginalURL,
+		UTM:          req.UTM,
+		ForwardQuery: req.ForwardQuery,
+		Variants:     req.Variants,
+		SocialCard:   req.SocialCard,
+	}, principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	setETag(w, link)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&link)
+}
+
+// patch applies the JSON Merge Patch of the body to the link, e.g.
+// {"title": "Launch", "notes": null} sets the title and clears the notes.
+func (h *LinkHandler) patch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		handleError(w, err, "The link was modified since it was retrieved")
+		return
+	}
+
+	var patch json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		handleError(w, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "json decode"),
+			"Invalid request format")
+		return
+	}
+
+	link, err := h.svc.Patch(r.Context(), mux.Vars(r)["hash"], patch, version, principal)
 	if err != nil {
 		handleError(w, err, "An internal error has occurred. Please try again later.")
 		return
 	}
 
+	setETag(w, link)
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(&link)
 }
 
 func (h *LinkHandler) delete(w http.ResponseWriter, r *http.Request) {
-	userID, err := h.auth.Authenticate(r, w)
+	principal, err := h.auth.Authenticate(r, w)
 	if err != nil {
 		handleError(w, err, "Invalid authentication credentials")
 		return
 	}
 
+	version, err := parseIfMatch(r)
+	if err != nil {
+		handleError(w, err, "The link was modified since it was retrieved")
+		return
+	}
+
 	vars := mux.Vars(r)
 
-	err = h.svc.Delete(r.Context(), vars["hash"], userID)
+	err = h.svc.Delete(r.Context(), vars["hash"], version, principal)
 	if err != nil {
 		handleError(w, err, "An internal error has occurred. Please try again later.")
 		return
@@ -144,3 +349,72 @@ func (h *LinkHandler) delete(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// trash lists the caller's deleted links that can still be restored.
+func (h *LinkHandler) trash(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	links, err := h.svc.Trash(r.Context(), principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&links)
+}
+
+func (h *LinkHandler) restore(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	link, err := h.svc.Restore(r.Context(), vars["hash"], principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	setETag(w, link)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&link)
+}
+
+// setETag exposes the version of the link as a strong entity tag, which
+// clients send back in If-Match to update or delete that version only.
+// Links stored before versioning have no version and no tag.
+func setETag(w http.ResponseWriter, link *domain.Link) {
+	if link.Version > 0 {
+		w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(link.Version, 10)))
+	}
+}
+
+// parseIfMatch returns the version required by the If-Match header, or zero
+// without one. "*" only requires the link to exist, which is checked
+// anyway. Other tags can't match the current version.
+func parseIfMatch(r *http.Request) (int64, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if len(value) == 0 || value == "*" {
+		return 0, nil
+	}
+
+	if tag, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+		if version, err := strconv.ParseInt(tag, 10, 64); err == nil && version > 0 {
+			return version, nil
+		}
+	}
+
+	return 0, util.NewErrorf(util.ErrCodePreconditionFailed, "if-match does not match the link version")
+}
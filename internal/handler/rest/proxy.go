@@ -0,0 +1,103 @@
+package rest
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// ParseTrustedProxies reads the addresses or CIDR ranges of the proxies in
+// front of the service, separated by commas or spaces, e.g.
+// "10.0.0.0/8, 192.168.1.10".
+func ParseTrustedProxies(value string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+
+	for _, entry := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "invalid trusted proxy %q", entry)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "invalid trusted proxy %q", entry)
+		}
+
+		proxies = append(proxies, network)
+	}
+
+	return proxies, nil
+}
+
+// TrustedProxyMiddleware replaces the remote address of requests relayed by
+// a trusted proxy with the client address from X-Forwarded-For. Every proxy
+// appends the address it received the request from, so the hops are read
+// from the right and the first one that isn't a trusted proxy is the
+// client; anything to its left may have been forged by the client.
+func TrustedProxyMiddleware(proxies []*net.IPNet) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(proxies) > 0 && isTrustedProxy(proxies, clientIP(r)) {
+				if ip := forwardedFor(r, proxies); len(ip) > 0 {
+					r.RemoteAddr = ip
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedFor returns the right-most address of X-Forwarded-For that isn't
+// a trusted proxy, or the left-most one when every hop is trusted. Malformed
+// headers are ignored.
+func forwardedFor(r *http.Request, proxies []*net.IPNet) string {
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+
+	client := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			return ""
+		}
+
+		client = ip.String()
+		if !isTrustedProxy(proxies, client) {
+			break
+		}
+	}
+
+	return client
+}
+
+func isTrustedProxy(proxies []*net.IPNet, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,173 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image/color"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+const (
+	qrCodeDefaultSize   = 256
+	qrCodeMinSize       = 64
+	qrCodeMaxSize       = 2048
+	qrCodeDefaultMargin = 4
+	qrCodeMaxMargin     = 16
+
+	// qrCodeCacheControl lets caches keep the image but revalidate it,
+	// so a link that is disabled or deleted stops serving its QR code.
+	qrCodeCacheControl = "no-cache"
+)
+
+type QRCodeHandler struct {
+	baseURL   string
+	generator port.QRCodeGenerator
+	svc       port.LinkService
+}
+
+func NewQRCodeHandler(baseURL string, generator port.QRCodeGenerator, svc port.LinkService) *QRCodeHandler {
+	return &QRCodeHandler{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		generator: generator,
+		svc:       svc,
+	}
+}
+
+func (h *QRCodeHandler) Register(r *mux.Router) {
+	r.HandleFunc("/api/shortlink/{hash}/qr", h.show).Methods(http.MethodGet)
+}
+
+func (h *QRCodeHandler) show(w http.ResponseWriter, r *http.Request) {
+	hash := mux.Vars(r)["hash"]
+
+	format := r.URL.Query().Get("format")
+	if len(format) == 0 {
+		format = "png"
+	}
+
+	var (
+		contentType string
+		render      func(content string, opts *domain.QRCodeOptions) ([]byte, error)
+	)
+
+	switch format {
+	case "png":
+		contentType, render = "image/png", h.generator.PNG
+	case "svg":
+		contentType, render = "image/svg+xml", h.generator.SVG
+	default:
+		handleError(w, util.NewErrorf(util.ErrCodeInvalidArgument, "unsupported format %q", format),
+			"Invalid QR code options")
+		return
+	}
+
+	opts, err := parseQRCodeOptions(r)
+	if err != nil {
+		handleError(w, err, "Invalid QR code options")
+		return
+	}
+
+	if _, err := h.svc.FindByHash(r.Context(), hash); err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	content := h.baseURL + "/" + hash
+	etag := qrCodeETag(content, format, opts)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.Header().Set("Cache-Control", qrCodeCacheControl)
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	image, err := render(content, opts)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", qrCodeCacheControl)
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(image)
+}
+
+func parseQRCodeOptions(r *http.Request) (*domain.QRCodeOptions, error) {
+	query := r.URL.Query()
+
+	opts := &domain.QRCodeOptions{
+		Size:       qrCodeDefaultSize,
+		Level:      domain.QRCodeLevelMedium,
+		Margin:     qrCodeDefaultMargin,
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+
+	if v := query.Get("size"); len(v) > 0 {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < qrCodeMinSize || size > qrCodeMaxSize {
+			return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "size must be between %d and %d", qrCodeMinSize, qrCodeMaxSize)
+		}
+		opts.Size = size
+	}
+
+	if v := query.Get("level"); len(v) > 0 {
+		switch level := strings.ToUpper(v); level {
+		case domain.QRCodeLevelLow, domain.QRCodeLevelMedium, domain.QRCodeLevelQuartile, domain.QRCodeLevelHigh:
+			opts.Level = level
+		default:
+			return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "level must be one of L, M, Q or H")
+		}
+	}
+
+	if v := query.Get("margin"); len(v) > 0 {
+		margin, err := strconv.Atoi(v)
+		if err != nil || margin < 0 || margin > qrCodeMaxMargin {
+			return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "margin must be between 0 and %d", qrCodeMaxMargin)
+		}
+		opts.Margin = margin
+	}
+
+	var err error
+	if v := query.Get("fg"); len(v) > 0 {
+		if opts.Foreground, err = parseHexColor(v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v := query.Get("bg"); len(v) > 0 {
+		if opts.Background, err = parseHexColor(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return opts, nil
+}
+
+// parseHexColor parses colors in the RRGGBB form, with or without a leading '#'.
+func parseHexColor(s string) (color.RGBA, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "#"))
+	if err != nil || len(b) != 3 {
+		return color.RGBA{}, util.NewErrorf(util.ErrCodeInvalidArgument, "invalid color %q", s)
+	}
+
+	return color.RGBA{R: b[0], G: b[1], B: b[2], A: 0xff}, nil
+}
+
+func qrCodeETag(content string, format string, opts *domain.QRCodeOptions) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%s|%d|%v|%v",
+		content, format, opts.Size, opts.Level, opts.Margin, opts.Foreground, opts.Background)))
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,188 @@
+package rest
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// RateLimitPolicy limits the requests to the routes matching Method and
+// Path, the route template such as /{hash}. A "*" matches any method.
+type RateLimitPolicy struct {
+	Method string
+	Path   string
+	Limit  domain.RateLimit
+}
+
+// ParseRateLimitPolicies reads policies separated by ";" in the form
+// "<method> <path>=<limit>/<period>[/<burst>]", e.g.
+// "POST /api/shortlink=30/1m; GET /{hash}=300/1m/600".
+func ParseRateLimitPolicies(value string) ([]RateLimitPolicy, error) {
+	var policies []RateLimitPolicy
+
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+
+		eq := strings.LastIndex(entry, "=")
+		if eq < 0 {
+			return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "invalid rate limit policy %q", entry)
+		}
+
+		route := strings.Fields(entry[:eq])
+		if len(route) != 2 {
+			return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "invalid rate limit policy %q", entry)
+		}
+
+		limit, err := ParseRateLimit(entry[eq+1:])
+		if err != nil {
+			return nil, err
+		}
+
+		policies = append(policies, RateLimitPolicy{
+			Method: strings.ToUpper(route[0]),
+			Path:   route[1],
+			Limit:  limit,
+		})
+	}
+
+	return policies, nil
+}
+
+// ParseRateLimit reads a limit in the form "<limit>/<period>[/<burst>]".
+func ParseRateLimit(value string) (domain.RateLimit, error) {
+	fields := strings.Split(strings.TrimSpace(value), "/")
+	if len(fields) < 2 || len(fields) > 3 {
+		return domain.RateLimit{}, util.NewErrorf(util.ErrCodeInvalidArgument, "invalid rate limit %q", value)
+	}
+
+	limit, err := strconv.Atoi(fields[0])
+	if err != nil || limit <= 0 {
+		return domain.RateLimit{}, util.NewErrorf(util.ErrCodeInvalidArgument, "invalid rate limit %q", value)
+	}
+
+	period, err := time.ParseDuration(fields[1])
+	if err != nil || period <= 0 {
+		return domain.RateLimit{}, util.NewErrorf(util.ErrCodeInvalidArgument, "invalid rate limit period %q", value)
+	}
+
+	rateLimit := domain.RateLimit{Limit: limit, Period: period}
+	if len(fields) == 3 {
+		if rateLimit.Burst, err = strconv.Atoi(fields[2]); err != nil || rateLimit.Burst <= 0 {
+			return domain.RateLimit{}, util.NewErrorf(util.ErrCodeInvalidArgument, "invalid rate limit burst %q", value)
+		}
+	}
+
+	return rateLimit, nil
+}
+
+// RateLimitMiddleware limits requests per route and per caller: the API key
+// or user of authenticated requests and the client IP otherwise. Routes
+// without a policy use fallback, when set. The authenticated principal is
+// kept in the request context for the handlers.
+//
+// Responses carry the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers, and Retry-After when the limit is exceeded. If
+// the limiter fails, requests are let through.
+func RateLimitMiddleware(auth port.Auth, limiter port.RateLimiter, policies []RateLimitPolicy,
+	fallback *domain.RateLimit) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name, limit := matchRateLimit(r, policies, fallback)
+			if limit == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			caller := "ip:" + clientIP(r)
+			if hasCredentials(r) {
+				if principal, err := auth.Authenticate(r, w); err == nil {
+					r = r.WithContext(domain.ContextWithPrincipal(r.Context(), principal))
+					caller = "user:" + principal.UserID
+					if len(principal.APIKeyID) > 0 {
+						caller = "key:" + principal.APIKeyID
+					}
+				}
+			}
+
+			decision, err := limiter.Allow(r.Context(), name+":"+caller, *limit)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(decision.ResetAfter)))
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Limit, seconds(limit.Period)))
+
+			if !decision.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(seconds(decision.RetryAfter)))
+				handleError(w, util.NewErrorf(util.ErrCodeTooManyRequests, "rate limit of %s exceeded", name),
+					"Too many requests. Please try again later.")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// matchRateLimit finds the policy of the matched route, preferring one for
+// the exact method.
+func matchRateLimit(r *http.Request, policies []RateLimitPolicy, fallback *domain.RateLimit) (string, *domain.RateLimit) {
+	var path string
+	if route := mux.CurrentRoute(r); route != nil {
+		path, _ = route.GetPathTemplate()
+	}
+
+	var match *RateLimitPolicy
+	for i, policy := range policies {
+		if policy.Path != path {
+			continue
+		}
+
+		if policy.Method == r.Method {
+			match = &policies[i]
+			break
+		}
+
+		if policy.Method == "*" && match == nil {
+			match = &policies[i]
+		}
+	}
+
+	if match != nil {
+		return match.Method + " " + match.Path, &match.Limit
+	}
+
+	return "default", fallback
+}
+
+// clientIP is the address of the connection, or of the client behind a
+// trusted proxy once TrustedProxyMiddleware has replaced it. Forwarding
+// headers are not read here since any client can set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// seconds rounds d up to whole seconds, as expected by the headers.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
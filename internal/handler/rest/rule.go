@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+func (h *LinkHandler) listRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	rules, err := h.svc.ListRules(r.Context(), mux.Vars(r)["hash"], principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	if rules == nil {
+		rules = []domain.RedirectRule{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&rules)
+}
+
+func (h *LinkHandler) replaceRules(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	var rules []domain.RedirectRule
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		handleError(w, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "json decode"),
+			"Invalid request format")
+		return
+	}
+
+	link, err := h.svc.ReplaceRules(r.Context(), mux.Vars(r)["hash"], rules, principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	setETag(w, link)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&link)
+}
+
+func (h *LinkHandler) addRule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	var rule domain.RedirectRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		handleError(w, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "json decode"),
+			"Invalid request format")
+		return
+	}
+
+	link, err := h.svc.AddRule(r.Context(), mux.Vars(r)["hash"], rule, principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	setETag(w, link)
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(&link)
+}
+
+func (h *LinkHandler) removeRule(w http.ResponseWriter, r *http.Request) {
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	vars := mux.Vars(r)
+	index, err := strconv.Atoi(vars["index"])
+	if err != nil {
+		handleError(w, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "rule index"),
+			"Invalid request format")
+		return
+	}
+
+	if _, err := h.svc.RemoveRule(r.Context(), vars["hash"], index, principal); err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
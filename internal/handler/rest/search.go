@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+// search finds the caller's links, or those of a workspace. It accepts the
+// tag (repeated or comma separated), domain, title, since, until,
+// workspace_id and limit parameters, with times in RFC 3339.
+func (h *LinkHandler) search(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	query, err := parseLinkQuery(r)
+	if err != nil {
+		handleError(w, err, "Invalid search query")
+		return
+	}
+
+	links, err := h.svc.Search(r.Context(), query, principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&links)
+}
+
+func parseLinkQuery(r *http.Request) (*domain.LinkQuery, error) {
+	values := r.URL.Query()
+
+	query := &domain.LinkQuery{
+		WorkspaceID: values.Get("workspace_id"),
+		Domain:      values.Get("domain"),
+		Title:       values.Get("title"),
+	}
+
+	for _, v := range values["tag"] {
+		query.Tags = append(query.Tags, strings.Split(v, ",")...)
+	}
+
+	if v := values.Get("limit"); len(v) > 0 {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return nil, util.NewErrorf(util.ErrCodeInvalidArgument, "limit must be a positive number")
+		}
+		query.Limit = limit
+	}
+
+	var err error
+	if v := values.Get("since"); len(v) > 0 {
+		if query.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "since must be an RFC 3339 time")
+		}
+	}
+
+	if v := values.Get("until"); len(v) > 0 {
+		if query.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "until must be an RFC 3339 time")
+		}
+	}
+
+	return query, nil
+}
@@ -0,0 +1,88 @@
+package rest
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+)
+
+// previewCrawlers are user agent fragments of the bots chat and social
+// apps use to build link previews.
+var previewCrawlers = []string{
+	"facebookexternalhit",
+	"facebot",
+	"twitterbot",
+	"slackbot",
+	"linkedinbot",
+	"discordbot",
+	"whatsapp",
+	"telegrambot",
+	"skypeuripreview",
+	"pinterestbot",
+	"redditbot",
+	"embedly",
+	"vkshare",
+	"mastodon",
+}
+
+var socialCardTemplate = template.Must(template.New("card").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:url" content="{{.URL}}">
+{{with .Title}}<meta property="og:title" content="{{.}}">
+<meta name="twitter:title" content="{{.}}">
+{{end}}{{with .Description}}<meta property="og:description" content="{{.}}">
+<meta name="twitter:description" content="{{.}}">
+{{end}}{{with .Image}}<meta property="og:image" content="{{.}}">
+<meta name="twitter:image" content="{{.}}">
+<meta name="twitter:card" content="summary_large_image">
+{{else}}<meta name="twitter:card" content="summary">
+{{end}}<meta http-equiv="refresh" content="0; url={{.URL}}">
+</head>
+<body><a href="{{.URL}}">{{.URL}}</a></body>
+</html>
+`))
+
+func isPreviewCrawler(userAgent string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, crawler := range previewCrawlers {
+		if strings.Contains(userAgent, crawler) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// renderSocialCard writes the card of the link, completing the fields it
+// doesn't override with the metadata fetched from the destination.
+func renderSocialCard(w http.ResponseWriter, redirect *domain.Redirect) {
+	card := *redirect.Link.SocialCard
+	if metadata := redirect.Link.Metadata; metadata != nil {
+		if len(card.Title) == 0 {
+			card.Title = metadata.Title
+		}
+
+		if len(card.Description) == 0 {
+			card.Description = metadata.Description
+		}
+
+		if len(card.Image) == 0 {
+			card.Image = metadata.Image
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	_ = socialCardTemplate.Execute(w, struct {
+		domain.SocialCard
+		URL string
+	}{card, redirect.URL})
+}
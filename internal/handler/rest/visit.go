@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/mileusna/useragent"
+)
+
+// countryHeaders are set by common CDNs and load balancers with the
+// visitor's ISO 3166-1 alpha-2 country code.
+var countryHeaders = []string{
+	"CF-IPCountry",
+	"CloudFront-Viewer-Country",
+	"X-AppEngine-Country",
+	"X-Country-Code",
+}
+
+func newVisit(r *http.Request, ua useragent.UserAgent, suffix string) *domain.Visit {
+	return &domain.Visit{
+		Query:     r.URL.Query(),
+		Suffix:    suffix,
+		OS:        ua.OS,
+		Device:    deviceClass(ua),
+		Country:   country(r),
+		Languages: languages(r.Header.Get("Accept-Language")),
+		Time:      time.Now(),
+	}
+}
+
+func deviceClass(ua useragent.UserAgent) string {
+	switch {
+	case ua.Bot:
+		return domain.DeviceBot
+	case ua.Tablet:
+		return domain.DeviceTablet
+	case ua.Mobile:
+		return domain.DeviceMobile
+	case ua.Desktop:
+		return domain.DeviceDesktop
+	}
+
+	return ""
+}
+
+func country(r *http.Request) string {
+	for _, header := range countryHeaders {
+		if value := r.Header.Get(header); len(value) > 0 {
+			return strings.ToUpper(value)
+		}
+	}
+
+	return ""
+}
+
+// languages returns the tags of an Accept-Language header ordered by quality.
+func languages(header string) []string {
+	type tag struct {
+		name    string
+		quality float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if len(fields[0]) == 0 || fields[0] == "*" {
+			continue
+		}
+
+		t := tag{name: fields[0], quality: 1}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					t.quality = v
+				}
+			}
+		}
+
+		tags = append(tags, t)
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].quality > tags[j].quality
+	})
+
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.name)
+	}
+
+	return names
+}
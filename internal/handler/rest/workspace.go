@@ -0,0 +1,174 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hugosrc/shortlink/internal/core/domain"
+	"github.com/hugosrc/shortlink/internal/core/port"
+	"github.com/hugosrc/shortlink/internal/util"
+)
+
+type WorkspaceHandler struct {
+	auth port.Auth
+	svc  port.WorkspaceService
+}
+
+func NewWorkspaceHandler(auth port.Auth, svc port.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		auth: auth,
+		svc:  svc,
+	}
+}
+
+func (h *WorkspaceHandler) Register(r *mux.Router) {
+	r.HandleFunc("/api/workspaces", h.create).Methods(http.MethodPost)
+	r.HandleFunc("/api/workspaces", h.list).Methods(http.MethodGet)
+	r.HandleFunc("/api/workspaces/{id}", h.show).Methods(http.MethodGet)
+	r.HandleFunc("/api/workspaces/{id}/members", h.listMembers).Methods(http.MethodGet)
+	r.HandleFunc("/api/workspaces/{id}/members/{user}", h.setMember).Methods(http.MethodPut)
+	r.HandleFunc("/api/workspaces/{id}/members/{user}", h.removeMember).Methods(http.MethodDelete)
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+func (h *WorkspaceHandler) create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	var req CreateWorkspaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "json decode"),
+			"Invalid request format")
+		return
+	}
+
+	workspace, err := h.svc.Create(r.Context(), req.Name, principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(&workspace)
+}
+
+func (h *WorkspaceHandler) list(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	workspaces, err := h.svc.List(r.Context(), principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	if workspaces == nil {
+		workspaces = []*domain.Workspace{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&workspaces)
+}
+
+func (h *WorkspaceHandler) show(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	workspace, err := h.svc.Get(r.Context(), mux.Vars(r)["id"], principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&workspace)
+}
+
+func (h *WorkspaceHandler) listMembers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	members, err := h.svc.ListMembers(r.Context(), mux.Vars(r)["id"], principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	if members == nil {
+		members = []*domain.Member{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&members)
+}
+
+type SetMemberRequest struct {
+	Role string `json:"role"`
+}
+
+func (h *WorkspaceHandler) setMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	var req SetMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, util.WrapErrorf(err, util.ErrCodeInvalidArgument, "json decode"),
+			"Invalid request format")
+		return
+	}
+
+	vars := mux.Vars(r)
+	member, err := h.svc.SetMember(r.Context(), vars["id"], vars["user"], req.Role, principal)
+	if err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(&member)
+}
+
+func (h *WorkspaceHandler) removeMember(w http.ResponseWriter, r *http.Request) {
+	principal, err := h.auth.Authenticate(r, w)
+	if err != nil {
+		handleError(w, err, "Invalid authentication credentials")
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := h.svc.RemoveMember(r.Context(), vars["id"], vars["user"], principal); err != nil {
+		handleError(w, err, "An internal error has occurred. Please try again later.")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -7,6 +7,10 @@ const (
 	ErrCodeNotFound
 	ErrCodeInvalidArgument
 	ErrCodeUnauthorized
+	ErrCodeForbidden
+	ErrCodeGone
+	ErrCodeTooManyRequests
+	ErrCodePreconditionFailed
 )
 
 type Error struct {
@@ -0,0 +1,69 @@
+package util
+
+import "net"
+
+// reservedNetworks are the special-purpose ranges not covered by the net.IP
+// predicates, which either aren't routed on the internet or translate to
+// addresses that may be internal.
+var reservedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",       // "this" network
+	"100.64.0.0/10",   // carrier-grade NAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // documentation
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // documentation
+	"203.0.113.0/24",  // documentation
+	"240.0.0.0/4",     // reserved, including the broadcast address
+	"::/96",           // IPv4-compatible addresses
+	"64:ff9b::/96",    // NAT64, which reaches any IPv4 address
+	"64:ff9b:1::/48",  // local-use NAT64
+	"100::/64",        // discard
+	"2001::/32",       // Teredo
+	"2001:db8::/32",   // documentation
+	"fec0::/10",       // deprecated site-local addresses
+)
+
+// sixToFour embeds an IPv4 address in its second to fifth bytes.
+var sixToFour = mustParseCIDRs("2002::/16")[0]
+
+// IsPublicIP reports whether ip is reachable on the public internet, as
+// opposed to loopback, private, link-local, multicast, shared or otherwise
+// reserved addresses. 6to4 addresses are public only if the IPv4 address
+// they embed is.
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	if ip.To4() == nil && sixToFour.Contains(ip) {
+		return IsPublicIP(net.IPv4(ip[2], ip[3], ip[4], ip[5]))
+	}
+
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks
+}
@@ -0,0 +1,58 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to the JSON document:
+// members of the patch replace those of the document, objects are merged
+// recursively and null members are removed.
+func MergePatch(doc []byte, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := decodeJSON(doc, &target); err != nil {
+		return nil, WrapErrorf(err, ErrCodeUnknown, "invalid json document")
+	}
+
+	var changes interface{}
+	if err := decodeJSON(patch, &changes); err != nil {
+		return nil, WrapErrorf(err, ErrCodeInvalidArgument, "invalid merge patch")
+	}
+
+	merged, err := json.Marshal(mergePatch(target, changes))
+	if err != nil {
+		return nil, WrapErrorf(err, ErrCodeUnknown, "json marshal error")
+	}
+
+	return merged, nil
+}
+
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	doc, ok := target.(map[string]interface{})
+	if !ok {
+		doc = map[string]interface{}{}
+	}
+
+	for name, value := range changes {
+		if value == nil {
+			delete(doc, name)
+		} else {
+			doc[name] = mergePatch(doc[name], value)
+		}
+	}
+
+	return doc
+}
+
+// decodeJSON keeps numbers as written, so large integers don't lose
+// precision on their way through the patch.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}